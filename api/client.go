@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Client is a client of pickup-ratings JSON API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// ResponseError is returned by Client when API responds with non-200 status
+type ResponseError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// NewClient creates API client for website at baseURL, e.g. https://ratings.example.com
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{baseURL: baseURL, httpClient: httpClient}
+}
+
+func (c *Client) PickupSites(ctx context.Context) ([]PickupSite, error) {
+	var v []PickupSite
+	if err := c.get(ctx, "/api/v1/sites", nil, &v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Leaderboard returns page of class leaderboard sorted by "rating" or "ordinal" rating, empty sortBy sorts by rating.
+// Provisional leaderboard lists players with too few games.
+func (c *Client) Leaderboard(ctx context.Context, pickupSite, class string, offset, limit int, sortBy string, provisional bool) (Leaderboard, error) {
+	query := url.Values{
+		"offset":      []string{strconv.Itoa(offset)},
+		"limit":       []string{strconv.Itoa(limit)},
+		"provisional": []string{strconv.FormatBool(provisional)},
+	}
+
+	if sortBy != "" {
+		query.Set("sort", sortBy)
+	}
+
+	var v Leaderboard
+	if err := c.get(ctx, "/api/v1/sites/"+url.PathEscape(pickupSite)+"/leaderboards/"+url.PathEscape(class), query, &v); err != nil {
+		return Leaderboard{}, err
+	}
+
+	return v, nil
+}
+
+func (c *Client) PlayerHistory(ctx context.Context, pickupSite string, steamID int64, class string) (PlayerHistory, error) {
+	path := fmt.Sprintf("/api/v1/sites/%s/players/%d/history/%s", url.PathEscape(pickupSite), steamID, url.PathEscape(class))
+
+	var v PlayerHistory
+	if err := c.get(ctx, path, nil, &v); err != nil {
+		return PlayerHistory{}, err
+	}
+
+	return v, nil
+}
+
+func (c *Client) Game(ctx context.Context, pickupSite string, gameID int64) (Game, error) {
+	path := fmt.Sprintf("/api/v1/sites/%s/games/%d", url.PathEscape(pickupSite), gameID)
+
+	var v Game
+	if err := c.get(ctx, path, nil, &v); err != nil {
+		return Game{}, err
+	}
+
+	return v, nil
+}
+
+// Predict returns win probabilities of proposed teams
+func (c *Client) Predict(ctx context.Context, pickupSite string, red, blu []LineupSlot) (Prediction, error) {
+	path := fmt.Sprintf("/api/v1/sites/%s/predict", url.PathEscape(pickupSite))
+
+	var v Prediction
+	if err := c.post(ctx, path, PredictionRequest{Red: red, Blu: blu}, &v); err != nil {
+		return Prediction{}, err
+	}
+
+	return v, nil
+}
+
+// Balance splits players into the fairest teams with the same classes
+func (c *Client) Balance(ctx context.Context, pickupSite string, players []LineupSlot) (Prediction, error) {
+	path := fmt.Sprintf("/api/v1/sites/%s/balance", url.PathEscape(pickupSite))
+
+	var v Prediction
+	if err := c.post(ctx, path, BalanceRequest{Players: players}, &v); err != nil {
+		return Prediction{}, err
+	}
+
+	return v, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("preparing http request: %w", err)
+	}
+
+	return c.do(req, v)
+}
+
+func (c *Client) post(ctx context.Context, path string, body, v any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("preparing http request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, v)
+}
+
+func (c *Client) do(req *http.Request, v any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr Error
+		if err = json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+
+		return &ResponseError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
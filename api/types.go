@@ -0,0 +1,121 @@
+// Package api contains types of pickup-ratings JSON API responses.
+//
+// Ratings are in the same units as on the website. Steam IDs are SteamID64 encoded as strings,
+// timestamps are in RFC 3339 format.
+package api
+
+type PickupSite struct {
+	Name    string   `json:"name"`
+	Classes []string `json:"classes"`
+}
+
+// LeaderboardEntry is a rating of player. OrdinalRating is conservative estimate of player's skill,
+// rating minus several uncertainties.
+type LeaderboardEntry struct {
+	Position      int     `json:"position"`
+	SteamID       int64   `json:"steamId,string"`
+	Name          string  `json:"name"`
+	AvatarURL     string  `json:"avatarUrl"`
+	Rating        float64 `json:"rating"`
+	Uncertainty   float64 `json:"uncertainty"`
+	OrdinalRating float64 `json:"ordinalRating"`
+	GamesPlayed   int64   `json:"gamesPlayed"`
+	GamesWon      int64   `json:"gamesWon"`
+	GamesTied     int64   `json:"gamesTied"`
+	Algorithm     string  `json:"algorithm"`
+}
+
+// Leaderboard is a page of class leaderboard. Provisional leaderboard lists players
+// with fewer games than required by pickup site.
+type Leaderboard struct {
+	PickupSite  string             `json:"pickupSite"`
+	Class       string             `json:"class"`
+	Offset      int                `json:"offset"`
+	Limit       int                `json:"limit"`
+	Total       int                `json:"total"`
+	SortBy      string             `json:"sort"`
+	Provisional bool               `json:"provisional"`
+	Entries     []LeaderboardEntry `json:"entries"`
+}
+
+// RatingUpdate is a rating of player after the game. RatingBefore, Uncertainty and UncertaintyBefore
+// are absent for some games rated before they were stored.
+type RatingUpdate struct {
+	GameID   int64   `json:"gameId"`
+	PickupID string  `json:"pickupId"`
+	Map      string  `json:"map"`
+	Rating   float64 `json:"rating"`
+	Result   string  `json:"result"`
+	RedScore int64   `json:"redScore"`
+	BluScore int64   `json:"bluScore"`
+	PlayedAt string  `json:"playedAt"`
+
+	RatingBefore      *float64 `json:"ratingBefore,omitempty"`
+	Uncertainty       *float64 `json:"uncertainty,omitempty"`
+	UncertaintyBefore *float64 `json:"uncertaintyBefore,omitempty"`
+}
+
+type PlayerHistory struct {
+	PickupSite string         `json:"pickupSite"`
+	SteamID    int64          `json:"steamId,string"`
+	Name       string         `json:"name"`
+	Class      string         `json:"class"`
+	Updates    []RatingUpdate `json:"updates"`
+}
+
+type GamePlayer struct {
+	SteamID int64  `json:"steamId,string"`
+	Name    string `json:"name"`
+	Team    string `json:"team"`
+	Class   string `json:"class"`
+}
+
+// Game is a game of pickup site. RedWinProbability is assigned by rating algorithm before the game,
+// it is absent for games that were not rated.
+type Game struct {
+	ID                int64        `json:"id"`
+	PickupID          string       `json:"pickupId"`
+	Map               string       `json:"map"`
+	State             string       `json:"state"`
+	RedScore          int64        `json:"redScore"`
+	BluScore          int64        `json:"bluScore"`
+	PlayedAt          string       `json:"playedAt"`
+	RedWinProbability *float64     `json:"redWinProbability,omitempty"`
+	Players           []GamePlayer `json:"players"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+// LineupSlot is a player of proposed lineup
+type LineupSlot struct {
+	SteamID int64  `json:"steamId,string"`
+	Class   string `json:"class"`
+}
+
+type PredictionRequest struct {
+	Red []LineupSlot `json:"red"`
+	Blu []LineupSlot `json:"blu"`
+}
+
+type BalanceRequest struct {
+	Players []LineupSlot `json:"players"`
+}
+
+// TeamPlayer is a player of predicted lineup with the rating used for prediction.
+// Players without games of the class have default rating and zero GamesPlayed.
+type TeamPlayer struct {
+	SteamID     int64   `json:"steamId,string"`
+	Class       string  `json:"class"`
+	Rating      float64 `json:"rating"`
+	GamesPlayed int64   `json:"gamesPlayed"`
+}
+
+type Prediction struct {
+	Algorithm         string       `json:"algorithm"`
+	RedWinProbability float64      `json:"redWinProbability"`
+	BluWinProbability float64      `json:"bluWinProbability"`
+	Red               []TeamPlayer `json:"red"`
+	Blu               []TeamPlayer `json:"blu"`
+}
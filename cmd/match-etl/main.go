@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	flag "github.com/spf13/pflag"
 
@@ -22,6 +24,15 @@ var (
 	gamesPageSize  int
 	startingOffset int
 	gameLimit      int
+	algorithm      string
+	decay          float64
+	marginCurve    string
+	marginCap      float64
+	watch          bool
+	interval       time.Duration
+	minGames       int
+	sideCorrection bool
+	force          bool
 )
 
 func main() {
@@ -29,12 +40,40 @@ func main() {
 	flag.IntVar(&gamesPageSize, "games-page-size", 200, "Amount of games per page for API requests")
 	flag.IntVar(&startingOffset, "offset", 0, "First game number to load if there is no games for pickup site")
 	flag.IntVar(&gameLimit, "max-games", 1000, "Max number of games loaded in single run")
+	flag.StringVar(&algorithm, "algorithm", collector.AlgorithmOpenSkill, fmt.Sprintf("Rating algorithm, one of %v", collector.Algorithms))
+	flag.Float64Var(&decay, "uncertainty-growth", 0, "Rating uncertainty after a day without games, its square is added to variance every day, 0 disables it")
+	flag.StringVar(&marginCurve, "margin-curve", collector.MarginCurveNone, fmt.Sprintf("Score margin weighting of rating changes, one of %v", collector.MarginCurves))
+	flag.Float64Var(&marginCap, "margin-cap", 2, "Max weight of decisive games with score margin weighting")
+	flag.BoolVar(&watch, "watch", false, "Keep running and collect new games every --interval")
+	flag.DurationVar(&interval, "interval", 5*time.Minute, "Delay between collection runs in watch mode")
+	flag.IntVar(&minGames, "min-games", 16, "Games players need to appear on leaderboards, saved by configure command")
+	flag.BoolVar(&sideCorrection, "side-correction", false, "Correct ratings for side advantage of maps estimated from previous games")
+	flag.BoolVar(&force, "force", false, "Recompute ratings even if some ended games have no stored lineup, ratings of such games are lost")
 	flag.Parse()
 
 	if pickupSite == "" {
 		log.Fatal("--pickup-site must be specified")
 	}
 
+	rater, err := collector.NewRater(algorithm)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	margin := collector.MarginWeighting{Curve: marginCurve, Cap: marginCap}
+	if err = margin.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	command := flag.Arg(0)
+	switch command {
+	case "":
+		command = "collect"
+	case "collect", "recompute", "decay", "configure":
+	default:
+		log.Fatalf("unknown command %q, expected collect, recompute, decay or configure", command)
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
@@ -52,11 +91,44 @@ func main() {
 
 	pickupApi := tf2pickup.NewClient(pickupSite, gamesPageSize, http.DefaultTransport)
 
-	c := collector.New(dbClient, pickupApi, pickupSite)
+	c := collector.New(collector.Database{Client: dbClient}, pickupApi, pickupSite, collector.Config{
+		Rater:             rater,
+		UncertaintyGrowth: decay,
+		Margin:            margin,
+		SideCorrection:    sideCorrection,
+	})
+
+	switch command {
+	case "collect":
+		if watch {
+			slog.Info("watching for new games", "interval", interval.String())
+
+			c.Watch(ctx, interval, startingOffset, gameLimit)
+			return
+		}
+
+		slog.Info("collecting games")
+
+		if err = c.CollectGames(ctx, startingOffset, gameLimit); err != nil {
+			log.Fatalf("failed to run collector: %s", err)
+		}
+	case "recompute":
+		slog.Info("recomputing ratings")
+
+		if err = c.Recompute(ctx, force); err != nil {
+			log.Fatalf("failed to recompute ratings: %s", err)
+		}
+	case "decay":
+		slog.Info("growing uncertainty of inactive players")
 
-	slog.Info("collecting games")
+		if err = c.DecayRatings(ctx); err != nil {
+			log.Fatalf("failed to decay ratings: %s", err)
+		}
+	case "configure":
+		slog.Info("configuring pickup site", "min_games", minGames)
 
-	if err = c.CollectGames(ctx, startingOffset, gameLimit); err != nil {
-		log.Fatalf("failed to run collector: %s", err)
+		if err = dbClient.SetPickupSiteMinGames(ctx, pickupSite, minGames); err != nil {
+			log.Fatalf("failed to configure pickup site: %s", err)
+		}
 	}
 }
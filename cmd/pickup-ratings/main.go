@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/condensedtea/pickup-ratings/internal/db"
 	"github.com/condensedtea/pickup-ratings/internal/http"
@@ -17,7 +18,20 @@ func main() {
 		log.Fatal(err)
 	}
 
-	server := http.NewServer(dbClient)
+	cfg := http.Config{OrdinalK: http.DefaultOrdinalK}
+	if inactiveDays, ok := os.LookupEnv("INACTIVE_DAYS"); ok {
+		if cfg.InactiveDays, err = strconv.Atoi(inactiveDays); err != nil {
+			log.Fatalf("failed to parse INACTIVE_DAYS: %s", err)
+		}
+	}
+
+	if ordinalK, ok := os.LookupEnv("ORDINAL_K"); ok {
+		if cfg.OrdinalK, err = strconv.ParseFloat(ordinalK, 64); err != nil {
+			log.Fatalf("failed to parse ORDINAL_K: %s", err)
+		}
+	}
+
+	server := http.NewServer(dbClient, cfg)
 
 	if err = server.Run(os.Getenv("PORT")); err != nil {
 		log.Fatalf("failed to run server: %s", err)
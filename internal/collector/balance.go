@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/samber/lo"
+)
+
+// ErrOddClassCount is returned by Balance when players of some class can't be split evenly between teams
+var ErrOddClassCount = errors.New("odd number of players of the class")
+
+// Balance splits players into red and blu teams with the same classes, so that win probability
+// predicted by rater is as close to even as possible. Every split is checked, which is fine for pickup sized lineups.
+func Balance(rater Rater, players []db.PlayerRating) (red, blu []db.PlayerRating, err error) {
+	byClass := lo.GroupBy(players, func(p db.PlayerRating) string {
+		return p.Class
+	})
+
+	classes := lo.Keys(byClass)
+	slices.SortFunc(classes, func(a, b string) int {
+		if d := classIndex(a) - classIndex(b); d != 0 {
+			return d
+		}
+
+		return cmp.Compare(a, b)
+	})
+
+	for _, class := range classes {
+		if len(byClass[class])%2 != 0 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrOddClassCount, class)
+		}
+	}
+
+	best := math.Inf(1)
+
+	var split func(i int, r, b []db.PlayerRating)
+	split = func(i int, r, b []db.PlayerRating) {
+		if i == len(classes) {
+			if d := math.Abs(rater.WinProbability(r, b) - 0.5); d < best {
+				best = d
+				red, blu = slices.Clone(r), slices.Clone(b)
+			}
+			return
+		}
+
+		group := byClass[classes[i]]
+
+		// mirrored splits are the same, so the first player always plays for red
+		for _, mask := range halfSubsets(len(group), i == 0) {
+			nextRed, nextBlu := r, b
+			for j, p := range group {
+				if mask&(1<<j) != 0 {
+					nextRed = append(nextRed, p)
+				} else {
+					nextBlu = append(nextBlu, p)
+				}
+			}
+
+			split(i+1, nextRed, nextBlu)
+		}
+	}
+
+	split(0, nil, nil)
+
+	return withTeam(red, "red"), withTeam(blu, "blu"), nil
+}
+
+// halfSubsets returns bit masks of all subsets of n elements with n/2 elements
+func halfSubsets(n int, withFirst bool) []uint32 {
+	var masks []uint32
+	for mask := uint32(0); mask < 1<<n; mask++ {
+		if bits.OnesCount32(mask) != n/2 || (withFirst && mask&1 == 0) {
+			continue
+		}
+
+		masks = append(masks, mask)
+	}
+
+	return masks
+}
+
+func withTeam(team []db.PlayerRating, name string) []db.PlayerRating {
+	for i := range team {
+		team[i].Team = name
+	}
+
+	return team
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/samber/lo"
+)
+
+func TestHalfSubsets(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		withFirst bool
+		want      []uint32
+	}{
+		{"empty", 0, false, []uint32{0}},
+		{"pair", 2, false, []uint32{0b01, 0b10}},
+		{"pair with first", 2, true, []uint32{0b01}},
+		{"four", 4, false, []uint32{0b0011, 0b0101, 0b0110, 0b1001, 0b1010, 0b1100}},
+		{"four with first", 4, true, []uint32{0b0011, 0b0101, 0b1001}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := halfSubsets(tt.n, tt.withFirst); !slices.Equal(got, tt.want) {
+				t.Errorf("halfSubsets(%d, %v) = %b, want %b", tt.n, tt.withFirst, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalance(t *testing.T) {
+	rater := eloRater{k: 0.32, scale: 4}
+
+	tests := []struct {
+		name             string
+		players          []db.PlayerRating
+		wantRed, wantBlu []float64
+		wantErr          error
+	}{
+		{
+			name:    "single class",
+			players: []db.PlayerRating{scout(10), scout(20), scout(30), scout(40)},
+			wantRed: []float64{10, 40},
+			wantBlu: []float64{20, 30},
+		},
+		{
+			name:    "two classes",
+			players: []db.PlayerRating{soldier(30), scout(10), soldier(50), scout(20)},
+			wantRed: []float64{10, 50},
+			wantBlu: []float64{20, 30},
+		},
+		{
+			name:    "odd class count",
+			players: []db.PlayerRating{scout(10), scout(20), soldier(30)},
+			wantErr: ErrOddClassCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			red, blu, err := Balance(rater, tt.players)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Balance() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			rating := func(p db.PlayerRating, _ int) float64 { return p.Rating }
+			if got := lo.Map(red, rating); !slices.Equal(got, tt.wantRed) {
+				t.Errorf("red = %v, want %v", got, tt.wantRed)
+			}
+			if got := lo.Map(blu, rating); !slices.Equal(got, tt.wantBlu) {
+				t.Errorf("blu = %v, want %v", got, tt.wantBlu)
+			}
+
+			class := func(p db.PlayerRating) string { return p.Class }
+			if redClasses, bluClasses := lo.CountValuesBy(red, class), lo.CountValuesBy(blu, class); !maps.Equal(redClasses, bluClasses) {
+				t.Errorf("red classes = %v, blu classes = %v, want the same", redClasses, bluClasses)
+			}
+
+			for _, p := range red {
+				if p.Team != "red" {
+					t.Errorf("red player team = %q", p.Team)
+				}
+			}
+			for _, p := range blu {
+				if p.Team != "blu" {
+					t.Errorf("blu player team = %q", p.Team)
+				}
+			}
+		})
+	}
+}
+
+func scout(rating float64) db.PlayerRating {
+	return db.PlayerRating{Class: "scout", Rating: rating}
+}
+
+func soldier(rating float64) db.PlayerRating {
+	return db.PlayerRating{Class: "soldier", Rating: rating}
+}
@@ -3,10 +3,12 @@ package collector
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/condensedtea/pickup-ratings/internal/db"
 	"github.com/condensedtea/pickup-ratings/internal/tf2pickup"
-	"github.com/eullerpereira94/openskill"
 	"github.com/jackc/pgx/v5"
 	"github.com/samber/lo"
 	"golang.org/x/exp/slog"
@@ -19,26 +21,82 @@ type database interface {
 	SaveGame(ctx context.Context, game db.Game) error
 	CreatePlayerRatings(ctx context.Context, ratings []db.PlayerRating, pickupSite string) error
 	GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int64, pickupSite string) ([]db.PlayerRating, error)
-	LogRatingUpdates(ctx context.Context, gameID int64, pickupSite string, ratings []db.PlayerRating, ts string) error
-	UpdatePlayerRatings(ctx context.Context, ratings []db.PlayerRating) error
+	LogRatingUpdates(ctx context.Context, gameID int64, pickupSite string, before, ratings []db.PlayerRating, ts string) error
+	UpdatePlayerRatings(ctx context.Context, ratings []db.PlayerRating, ts string) error
+	InflateUncertainty(ctx context.Context, pickupSite string, steamIDs []int64, ts string, growthPerDay, maxUncertainty float64) error
+	SaveGamePlayers(ctx context.Context, gameID int64, pickupSite string, players []db.GamePlayer) error
+	GetGames(ctx context.Context, pickupSite string) ([]db.Game, error)
+	GetGamePlayers(ctx context.Context, pickupSite string) ([]db.GamePlayer, error)
+	GetPendingGames(ctx context.Context, pickupSite string, states []string) ([]db.Game, error)
+	AddPickupSiteClasses(ctx context.Context, pickupSite string, classes []string) error
+	SetGameWinProbability(ctx context.Context, pickupSite string, gameID int64, redWinProbability float64) error
+	GetMapStatsBefore(ctx context.Context, pickupSite, gameMap string, gameID int64) (db.MapStats, error)
+	DeletePlayerRatings(ctx context.Context, pickupSite string) error
+	WithTx(ctx context.Context, fn func(tx database) error) error
+}
+
+// Database binds db.Client to the collector, so queries inside a transaction
+// go through the same database interface as the rest of them
+type Database struct {
+	*db.Client
+}
+
+func (d Database) WithTx(ctx context.Context, fn func(tx database) error) error {
+	return d.Client.WithTx(ctx, func(tx *db.Client) error {
+		return fn(Database{Client: tx})
+	})
 }
 
 type pickupAPI interface {
 	LoadNewGames(ctx context.Context, offset, limit int) ([]tf2pickup.Result, error)
+	LoadGame(ctx context.Context, id string) (tf2pickup.Result, error)
+}
+
+// ongoingStates are states of games that are not finished yet. Such games are saved as pending
+// and loaded again on subsequent runs until they reach terminal state.
+var ongoingStates = []string{"created", "configuring", "launching", "started"}
+
+type Config struct {
+	Rater Rater
+	// UncertaintyGrowth is uncertainty gained by player's rating after a day without games. Its square is added
+	// to rating variance every day, so uncertainty grows with square root of inactive days. Zero disables inactivity handling.
+	UncertaintyGrowth float64
+	Margin            MarginWeighting
+	// SideCorrection rates games with virtual rating offset of the side that wins more often on the map
+	SideCorrection bool
 }
 
 type Collector struct {
 	pickupSite string
 
-	db  database
-	api pickupAPI
+	db    database
+	api   pickupAPI
+	rater Rater
+
+	uncertaintyGrowth float64
+	margin            MarginWeighting
+	sideCorrection    bool
 }
 
-func New(db database, api pickupAPI, pickupSite string) *Collector {
-	return &Collector{db: db, api: api, pickupSite: pickupSite}
+func New(db database, api pickupAPI, pickupSite string, cfg Config) *Collector {
+	return &Collector{
+		db:                db,
+		api:               api,
+		pickupSite:        pickupSite,
+		rater:             cfg.Rater,
+		uncertaintyGrowth: cfg.UncertaintyGrowth,
+		margin:            cfg.Margin,
+		sideCorrection:    cfg.SideCorrection,
+	}
 }
 
+// CollectGames rates pending games that have finished since the previous run and then loads and rates new games.
+// Pending games are rated in game order before any new game.
 func (c *Collector) CollectGames(ctx context.Context, startingOffset, gameLimit int) error {
+	if err := c.collectPendingGames(ctx); err != nil {
+		return err
+	}
+
 	offset, err := c.db.GetLastGameID(ctx, c.pickupSite)
 	if errors.Is(err, pgx.ErrNoRows) {
 		// if no game recorded
@@ -54,7 +112,7 @@ func (c *Collector) CollectGames(ctx context.Context, startingOffset, gameLimit
 
 	for _, game := range games {
 		slog.Info("processing game", "number", game.Number)
-		if err = c.processGame(ctx, game); err != nil {
+		if err = c.saveGame(ctx, game); err != nil {
 			return err
 		}
 	}
@@ -62,12 +120,65 @@ func (c *Collector) CollectGames(ctx context.Context, startingOffset, gameLimit
 	return nil
 }
 
-func (c *Collector) processGame(ctx context.Context, game tf2pickup.Result) (err error) {
-	// handle ongoing games
-	if game.State == "started" {
-		return nil
+func (c *Collector) collectPendingGames(ctx context.Context) error {
+	pendingGames, err := c.db.GetPendingGames(ctx, c.pickupSite, ongoingStates)
+	if err != nil {
+		return err
+	}
+
+	for _, pendingGame := range pendingGames {
+		// pending game may be deleted or broken on pickup site, it must not block collection of new games
+		game, err := c.api.LoadGame(ctx, pendingGame.PickupID)
+		if err != nil {
+			slog.Warn("failed to load pending game", "number", pendingGame.ID, "error", err)
+			continue
+		}
+
+		if lo.Contains(ongoingStates, game.State) {
+			continue
+		}
+
+		slog.Info("processing pending game", "number", game.Number, "state", game.State)
+		if err = c.saveGame(ctx, game); err != nil {
+			return err
+		}
 	}
 
+	return nil
+}
+
+// saveGame processes game in its own transaction, so interrupted run
+// never leaves game recorded without its rating updates
+func (c *Collector) saveGame(ctx context.Context, game tf2pickup.Result) error {
+	err := c.db.WithTx(ctx, func(tx database) error {
+		return c.processGame(ctx, tx, game)
+	})
+	if err != nil {
+		return fmt.Errorf("processing game %d: %w", game.Number, err)
+	}
+
+	return nil
+}
+
+// Watch runs CollectGames every interval until ctx is cancelled. Failed runs are logged and retried on next tick.
+func (c *Collector) Watch(ctx context.Context, interval time.Duration, startingOffset, gameLimit int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CollectGames(ctx, startingOffset, gameLimit); err != nil && ctx.Err() == nil {
+			slog.Error("failed to collect games", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Collector) processGame(ctx context.Context, tx database, game tf2pickup.Result) (err error) {
 	dbGame := db.Game{
 		ID:         game.Number,
 		Map:        game.Map,
@@ -76,37 +187,58 @@ func (c *Collector) processGame(ctx context.Context, game tf2pickup.Result) (err
 		BluScore:   game.Score.Blu,
 		Ts:         game.EndedAt,
 		PickupID:   game.Id,
+		State:      game.State,
 	}
 
-	if err = c.db.SaveGame(ctx, dbGame); err != nil {
+	// ongoing games have no end time yet
+	if dbGame.Ts == "" {
+		dbGame.Ts = game.LaunchedAt
+	}
+
+	if err = tx.SaveGame(ctx, dbGame); err != nil {
+		return err
+	}
+
+	players := newPlayerSet(playersFromSlots(game.Slots))
+
+	// lineups are kept for broken games too, even though they are not rated
+	if err = tx.SaveGamePlayers(ctx, game.Number, c.pickupSite, players.lineup(game.Number)); err != nil {
 		return err
 	}
 
+	// handle ongoing games, they are rated once they end
+	if lo.Contains(ongoingStates, game.State) {
+		slog.Info("saved ongoing game as pending", "state", game.State, "game_number", game.Number)
+		return nil
+	}
+
 	// handle broken games
 	if game.State != "ended" {
 		slog.Info("ignored game with broken state", "state", game.State, "game_number", game.Number)
 		return nil
 	}
 
-	players := newPlayerSet(game.Slots)
-
-	newSteamIDs, err := c.createNewPlayers(ctx, players)
-	if err != nil {
+	if err = c.createNewPlayers(ctx, tx, players); err != nil {
 		return err
 	}
 
-	newRatings := lo.Map(newSteamIDs, func(steamID int64, _ int) db.PlayerRating {
-		return defaultRating(players.bySteamID(steamID))
-	})
+	slog.Debug("new players created")
+
+	return c.rateGame(ctx, tx, dbGame, players)
+}
 
-	if err = c.db.CreatePlayerRatings(ctx, newRatings, c.pickupSite); err != nil {
+// rateGame calculates and saves rating changes for all players of the game
+func (c *Collector) rateGame(ctx context.Context, tx database, game db.Game, players playerSet) error {
+	if err := c.createMissingRatings(ctx, tx, players); err != nil {
 		return err
 	}
 
-	slog.Debug("new players created")
+	if err := c.inflateUncertainty(ctx, tx, players.steamIDs, game.Ts); err != nil {
+		return err
+	}
 
 	// calculate ratings diffs
-	steamIDRatings, err := c.db.GetPlayerRatingsForSteamIDs(ctx, players.steamIDs, c.pickupSite)
+	steamIDRatings, err := tx.GetPlayerRatingsForSteamIDs(ctx, players.steamIDs, c.pickupSite)
 	if err != nil {
 		return err
 	}
@@ -119,19 +251,43 @@ func (c *Collector) processGame(ctx context.Context, game tf2pickup.Result) (err
 
 	redRating, bluRating := teamRatings["red"], teamRatings["blu"]
 
-	newBluRating, newRedRating := rateTeams(redRating, bluRating, game.Score.Red, game.Score.Blu)
+	for _, r := range playerRatings {
+		if r.Algorithm != c.rater.Name() {
+			return fmt.Errorf("leaderboard %d is rated with %s, recompute ratings to switch to %s", r.ID, r.Algorithm, c.rater.Name())
+		}
+	}
+
+	before := append(slices.Clone(redRating), bluRating...)
+
+	var offset float64
+	if c.sideCorrection {
+		advantage, err := c.sideAdvantage(ctx, tx, game)
+		if err != nil {
+			return err
+		}
+
+		offset = sideOffset(c.rater, redRating, bluRating, advantage)
+		redRating, bluRating = shiftRatings(redRating, offset/2), shiftRatings(bluRating, -offset/2)
+	}
+
+	if err = tx.SetGameWinProbability(ctx, c.pickupSite, game.ID, c.rater.WinProbability(redRating, bluRating)); err != nil {
+		return err
+	}
 
-	ratings := append(newRedRating, newBluRating...)
+	newRedRating, newBluRating := c.rateTeams(redRating, bluRating, game.RedScore, game.BluScore)
+
+	// side offset is only used for rating the game and is not saved
+	ratings := append(shiftRatings(newRedRating, -offset/2), shiftRatings(newBluRating, offset/2)...)
 
 	slog.Debug("new ratings calculated")
 
-	if err = c.db.LogRatingUpdates(ctx, game.Number, c.pickupSite, ratings, dbGame.Ts); err != nil {
+	if err = tx.LogRatingUpdates(ctx, game.ID, c.pickupSite, before, ratings, game.Ts); err != nil {
 		return err
 	}
 
 	slog.Debug("ratings logged")
 
-	if err = c.db.UpdatePlayerRatings(ctx, ratings); err != nil {
+	if err = tx.UpdatePlayerRatings(ctx, ratings, game.Ts); err != nil {
 		return err
 	}
 
@@ -140,28 +296,24 @@ func (c *Collector) processGame(ctx context.Context, game tf2pickup.Result) (err
 	return nil
 }
 
-func rateTeams(t1, t2 []db.PlayerRating, redScore, bluScore int64) ([]db.PlayerRating, []db.PlayerRating) {
-	t1Ratings := playerRatingsToOpenSkillTeam(t1)
-	t2Ratings := playerRatingsToOpenSkillTeam(t2)
+// rateTeams rates the game with collector's Rater and updates players' game counters
+func (c *Collector) rateTeams(red, blu []db.PlayerRating, redScore, bluScore int64) ([]db.PlayerRating, []db.PlayerRating) {
+	// raters may update ratings in place
+	redBefore, bluBefore := slices.Clone(red), slices.Clone(blu)
 
-	teams := openskill.Rate([]openskill.Team{t1Ratings, t2Ratings}, openskill.Options{Scores: []int64{redScore, bluScore}})
+	red, blu = c.rater.Rate(red, blu, redScore, bluScore)
 
-	t1Ratings, t2Ratings = teams[0], teams[1]
+	if w := c.margin.weight(redScore, bluScore); w != 1 {
+		red, blu = scaleRatingChanges(redBefore, red, w), scaleRatingChanges(bluBefore, blu, w)
+	}
 
 	redResult, bluResult := gameResults(redScore, bluScore)
 
-	t1 = updateTeamRatings(t1, t1Ratings, redResult)
-	t2 = updateTeamRatings(t2, t2Ratings, bluResult)
-
-	return t1, t2
+	return updateTeamResults(red, redResult), updateTeamResults(blu, bluResult)
 }
 
-func updateTeamRatings(team []db.PlayerRating, ratings openskill.Team, result string) []db.PlayerRating {
+func updateTeamResults(team []db.PlayerRating, result string) []db.PlayerRating {
 	for i, p := range team {
-		playerRatings := ratings[i]
-
-		p.Rating = playerRatings.AveragePlayerSkill
-		p.UncertaintyValue = playerRatings.SkillUncertaintyDegree
 		p.Result = result
 		p.GamesPlayed++
 
@@ -178,22 +330,10 @@ func updateTeamRatings(team []db.PlayerRating, ratings openskill.Team, result st
 	return team
 }
 
-func playerRatingsToOpenSkillTeam(team []db.PlayerRating) openskill.Team {
-	var ratings = make([]*openskill.Rating, len(team))
-	for i, p := range team {
-		ratings[i] = openskill.NewRating(&openskill.NewRatingParams{
-			AveragePlayerSkill:     p.Rating,
-			SkillUncertaintyDegree: p.UncertaintyValue,
-		}, nil)
-	}
-
-	return openskill.NewTeam(ratings...)
-}
-
-func (c *Collector) createNewPlayers(ctx context.Context, players playerSet) (newSteamIDs []int64, err error) {
-	unknownSteamIDs, err := c.db.GetUnknownSteamIDs(ctx, players.steamIDs, c.pickupSite)
+func (c *Collector) createNewPlayers(ctx context.Context, tx database, players playerSet) error {
+	unknownSteamIDs, err := tx.GetUnknownSteamIDs(ctx, players.steamIDs, c.pickupSite)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var dbPlayers = make([]db.Player, len(unknownSteamIDs))
@@ -207,11 +347,33 @@ func (c *Collector) createNewPlayers(ctx context.Context, players playerSet) (ne
 		}
 	}
 
-	if err = c.db.CreatePlayersBatch(ctx, dbPlayers, c.pickupSite); err != nil {
-		return nil, err
+	return tx.CreatePlayersBatch(ctx, dbPlayers, c.pickupSite)
+}
+
+// createMissingRatings creates default ratings for players who have not played their class of the game yet,
+// so leaderboards exist only for classes that are actually played on pickup site
+func (c *Collector) createMissingRatings(ctx context.Context, tx database, players playerSet) error {
+	if err := tx.AddPickupSiteClasses(ctx, c.pickupSite, players.classes()); err != nil {
+		return err
+	}
+
+	steamIDRatings, err := tx.GetPlayerRatingsForSteamIDs(ctx, players.steamIDs, c.pickupSite)
+	if err != nil {
+		return err
+	}
+
+	rated := lo.SliceToMap(players.filterRatingsByClass(steamIDRatings), func(r db.PlayerRating) (int64, struct{}) {
+		return r.SteamID, struct{}{}
+	})
+
+	var newRatings []db.PlayerRating
+	for _, steamID := range players.steamIDs {
+		if _, ok := rated[steamID]; !ok {
+			newRatings = append(newRatings, defaultRating(c.rater, players.bySteamID(steamID)))
+		}
 	}
 
-	return unknownSteamIDs, nil
+	return tx.CreatePlayerRatings(ctx, newRatings, c.pickupSite)
 }
 
 func gameResults(redScore, bluScore int64) (redResult, bluResult string) {
@@ -0,0 +1,398 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/condensedtea/pickup-ratings/internal/tf2pickup"
+	"github.com/jackc/pgx/v5"
+)
+
+const testPickupSite = "tf2pickup.test"
+
+var errFake = errors.New("fake failure")
+
+// fakeDatabase keeps collector state in memory. Transactions work on a copy of the state,
+// which replaces the committed one only if transaction function succeeds.
+type fakeDatabase struct {
+	games   map[int64]db.Game
+	lineups map[int64][]db.GamePlayer
+	players map[int64]db.Player
+	ratings []db.PlayerRating
+	updates []db.PlayerRating
+	classes []string
+
+	// failOn is the name of the method that fails
+	failOn string
+}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{
+		games:   map[int64]db.Game{},
+		lineups: map[int64][]db.GamePlayer{},
+		players: map[int64]db.Player{},
+	}
+}
+
+func (f *fakeDatabase) clone() *fakeDatabase {
+	return &fakeDatabase{
+		games:   maps.Clone(f.games),
+		lineups: maps.Clone(f.lineups),
+		players: maps.Clone(f.players),
+		ratings: slices.Clone(f.ratings),
+		updates: slices.Clone(f.updates),
+		classes: slices.Clone(f.classes),
+		failOn:  f.failOn,
+	}
+}
+
+func (f *fakeDatabase) fail(method string) error {
+	if f.failOn == method {
+		return errFake
+	}
+
+	return nil
+}
+
+func (f *fakeDatabase) WithTx(ctx context.Context, fn func(tx database) error) error {
+	tx := f.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	*f = *tx
+
+	return nil
+}
+
+func (f *fakeDatabase) GetLastGameID(ctx context.Context, pickupSite string) (int, error) {
+	if len(f.games) == 0 {
+		return 0, pgx.ErrNoRows
+	}
+
+	var last int64
+	for id := range f.games {
+		last = max(last, id)
+	}
+
+	return int(last), nil
+}
+
+func (f *fakeDatabase) GetUnknownSteamIDs(ctx context.Context, steamIDs []int64, pickupSite string) ([]int64, error) {
+	var unknown []int64
+	for _, steamID := range steamIDs {
+		if _, ok := f.players[steamID]; !ok {
+			unknown = append(unknown, steamID)
+		}
+	}
+
+	return unknown, nil
+}
+
+func (f *fakeDatabase) CreatePlayersBatch(ctx context.Context, players []db.Player, pickupSite string) error {
+	for _, p := range players {
+		f.players[p.SteamID] = p
+	}
+
+	return f.fail("CreatePlayersBatch")
+}
+
+func (f *fakeDatabase) SaveGame(ctx context.Context, game db.Game) error {
+	f.games[game.ID] = game
+	return f.fail("SaveGame")
+}
+
+func (f *fakeDatabase) CreatePlayerRatings(ctx context.Context, ratings []db.PlayerRating, pickupSite string) error {
+	for _, r := range ratings {
+		r.ID = int64(len(f.ratings) + 1)
+		f.ratings = append(f.ratings, r)
+	}
+
+	return f.fail("CreatePlayerRatings")
+}
+
+func (f *fakeDatabase) GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int64, pickupSite string) ([]db.PlayerRating, error) {
+	var ratings []db.PlayerRating
+	for _, r := range f.ratings {
+		if slices.Contains(steamIDs, r.SteamID) {
+			ratings = append(ratings, r)
+		}
+	}
+
+	return ratings, nil
+}
+
+func (f *fakeDatabase) LogRatingUpdates(ctx context.Context, gameID int64, pickupSite string, before, ratings []db.PlayerRating, ts string) error {
+	f.updates = append(f.updates, ratings...)
+	return f.fail("LogRatingUpdates")
+}
+
+func (f *fakeDatabase) UpdatePlayerRatings(ctx context.Context, ratings []db.PlayerRating, ts string) error {
+	for _, r := range ratings {
+		i := slices.IndexFunc(f.ratings, func(pr db.PlayerRating) bool { return pr.ID == r.ID })
+		f.ratings[i] = r
+	}
+
+	return f.fail("UpdatePlayerRatings")
+}
+
+func (f *fakeDatabase) InflateUncertainty(ctx context.Context, pickupSite string, steamIDs []int64, ts string, growthPerDay, maxUncertainty float64) error {
+	return f.fail("InflateUncertainty")
+}
+
+func (f *fakeDatabase) SaveGamePlayers(ctx context.Context, gameID int64, pickupSite string, players []db.GamePlayer) error {
+	f.lineups[gameID] = players
+	return f.fail("SaveGamePlayers")
+}
+
+func (f *fakeDatabase) GetGames(ctx context.Context, pickupSite string) ([]db.Game, error) {
+	games := make([]db.Game, 0, len(f.games))
+	for _, g := range f.games {
+		games = append(games, g)
+	}
+
+	slices.SortFunc(games, func(a, b db.Game) int { return int(a.ID - b.ID) })
+
+	return games, nil
+}
+
+func (f *fakeDatabase) GetGamePlayers(ctx context.Context, pickupSite string) ([]db.GamePlayer, error) {
+	var players []db.GamePlayer
+	for _, lineup := range f.lineups {
+		players = append(players, lineup...)
+	}
+
+	return players, nil
+}
+
+func (f *fakeDatabase) GetPendingGames(ctx context.Context, pickupSite string, states []string) ([]db.Game, error) {
+	var pending []db.Game
+	for _, g := range f.games {
+		if slices.Contains(states, g.State) {
+			pending = append(pending, g)
+		}
+	}
+
+	slices.SortFunc(pending, func(a, b db.Game) int { return int(a.ID - b.ID) })
+
+	return pending, nil
+}
+
+func (f *fakeDatabase) AddPickupSiteClasses(ctx context.Context, pickupSite string, classes []string) error {
+	for _, class := range classes {
+		if !slices.Contains(f.classes, class) {
+			f.classes = append(f.classes, class)
+		}
+	}
+
+	return f.fail("AddPickupSiteClasses")
+}
+
+func (f *fakeDatabase) SetGameWinProbability(ctx context.Context, pickupSite string, gameID int64, redWinProbability float64) error {
+	g := f.games[gameID]
+	g.RedWinProbability = &redWinProbability
+	f.games[gameID] = g
+
+	return f.fail("SetGameWinProbability")
+}
+
+func (f *fakeDatabase) GetMapStatsBefore(ctx context.Context, pickupSite, gameMap string, gameID int64) (db.MapStats, error) {
+	return db.MapStats{Map: gameMap}, f.fail("GetMapStatsBefore")
+}
+
+func (f *fakeDatabase) DeletePlayerRatings(ctx context.Context, pickupSite string) error {
+	f.ratings, f.updates = nil, nil
+	return f.fail("DeletePlayerRatings")
+}
+
+// fakePickupAPI serves games by their number, games listed in failing return error when loaded by pickup ID
+type fakePickupAPI struct {
+	games   []tf2pickup.Result
+	failing []string
+}
+
+func (a *fakePickupAPI) LoadNewGames(ctx context.Context, offset, limit int) ([]tf2pickup.Result, error) {
+	var games []tf2pickup.Result
+	for _, g := range a.games {
+		if g.Number > int64(offset) && len(games) < limit {
+			games = append(games, g)
+		}
+	}
+
+	return games, nil
+}
+
+func (a *fakePickupAPI) LoadGame(ctx context.Context, id string) (tf2pickup.Result, error) {
+	if slices.Contains(a.failing, id) {
+		return tf2pickup.Result{}, errFake
+	}
+
+	for _, g := range a.games {
+		if g.Id == id {
+			return g, nil
+		}
+	}
+
+	return tf2pickup.Result{}, errors.New("game not found")
+}
+
+func testGame(number int64, state string, red, blu int64) tf2pickup.Result {
+	slot := func(steamID int64, team, class string) tf2pickup.Slot {
+		return tf2pickup.Slot{Player: tf2pickup.Player{SteamId: steamID, Name: "player"}, Team: team, GameClass: class}
+	}
+
+	return tf2pickup.Result{
+		Id:         fmt.Sprintf("game-%d", number),
+		Map:        "cp_process_final",
+		LaunchedAt: "2023-10-01T18:00:00Z",
+		EndedAt:    "2023-10-01T18:30:00Z",
+		Number:     number,
+		State:      state,
+		Score:      tf2pickup.Score{Red: red, Blu: blu},
+		Slots: []tf2pickup.Slot{
+			slot(76561198011558250, "red", "scout"),
+			slot(76561198011558251, "red", "soldier"),
+			slot(76561198011558252, "blu", "scout"),
+			slot(76561198011558253, "blu", "soldier"),
+		},
+	}
+}
+
+func newTestCollector(t *testing.T, database database, api pickupAPI) *Collector {
+	t.Helper()
+
+	rater, err := NewRater(AlgorithmOpenSkill)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(database, api, testPickupSite, Config{Rater: rater})
+}
+
+func TestCollectGames(t *testing.T) {
+	database := newFakeDatabase()
+	api := &fakePickupAPI{games: []tf2pickup.Result{testGame(1, "ended", 5, 0), testGame(2, "ended", 2, 3)}}
+
+	if err := newTestCollector(t, database, api).CollectGames(context.Background(), 0, 10); err != nil {
+		t.Fatalf("CollectGames() error = %v", err)
+	}
+
+	if len(database.games) != 2 || len(database.lineups) != 2 {
+		t.Errorf("saved %d games and %d lineups, want 2 of each", len(database.games), len(database.lineups))
+	}
+	if len(database.ratings) != 4 || len(database.updates) != 8 {
+		t.Errorf("saved %d ratings and %d rating updates, want 4 and 8", len(database.ratings), len(database.updates))
+	}
+	for _, r := range database.ratings {
+		if r.GamesPlayed != 2 || r.GamesWon != 1 {
+			t.Errorf("player %d has played %d games and won %d, want 2 and 1", r.SteamID, r.GamesPlayed, r.GamesWon)
+		}
+	}
+}
+
+func TestCollectGamesRollsBackFailedGame(t *testing.T) {
+	for _, method := range []string{"SaveGame", "SaveGamePlayers", "CreatePlayersBatch", "SetGameWinProbability", "LogRatingUpdates", "UpdatePlayerRatings"} {
+		t.Run(method, func(t *testing.T) {
+			database := newFakeDatabase()
+			api := &fakePickupAPI{games: []tf2pickup.Result{testGame(1, "ended", 5, 0)}}
+
+			database.failOn = method
+
+			err := newTestCollector(t, database, api).CollectGames(context.Background(), 0, 10)
+			if !errors.Is(err, errFake) {
+				t.Fatalf("CollectGames() error = %v, want %v", err, errFake)
+			}
+
+			if len(database.games) != 0 || len(database.lineups) != 0 || len(database.players) != 0 {
+				t.Errorf("committed %d games, %d lineups and %d players of failed game", len(database.games), len(database.lineups), len(database.players))
+			}
+			if len(database.ratings) != 0 || len(database.updates) != 0 {
+				t.Errorf("committed %d ratings and %d rating updates of failed game", len(database.ratings), len(database.updates))
+			}
+		})
+	}
+}
+
+func TestRecompute(t *testing.T) {
+	database := newFakeDatabase()
+	api := &fakePickupAPI{games: []tf2pickup.Result{testGame(1, "ended", 5, 0), testGame(2, "ended", 2, 3)}}
+	c := newTestCollector(t, database, api)
+
+	if err := c.CollectGames(context.Background(), 0, 10); err != nil {
+		t.Fatalf("CollectGames() error = %v", err)
+	}
+
+	collected := slices.Clone(database.ratings)
+
+	if err := c.Recompute(context.Background(), false); err != nil {
+		t.Fatalf("Recompute() error = %v", err)
+	}
+
+	if !slices.EqualFunc(collected, database.ratings, func(a, b db.PlayerRating) bool {
+		return a.SteamID == b.SteamID && a.Class == b.Class && a.Rating == b.Rating && a.GamesPlayed == b.GamesPlayed
+	}) {
+		t.Errorf("recomputed ratings %v, want %v", database.ratings, collected)
+	}
+}
+
+func TestRecomputeGamesWithoutLineup(t *testing.T) {
+	database := newFakeDatabase()
+	api := &fakePickupAPI{games: []tf2pickup.Result{testGame(1, "ended", 5, 0), testGame(2, "ended", 2, 3)}}
+	c := newTestCollector(t, database, api)
+
+	if err := c.CollectGames(context.Background(), 0, 10); err != nil {
+		t.Fatalf("CollectGames() error = %v", err)
+	}
+
+	// game collected before lineups were stored
+	delete(database.lineups, 1)
+
+	if err := c.Recompute(context.Background(), false); err == nil {
+		t.Fatal("Recompute() succeeded with game without lineup")
+	}
+	if len(database.ratings) != 4 || len(database.updates) != 8 {
+		t.Errorf("failed recompute left %d ratings and %d rating updates, want 4 and 8", len(database.ratings), len(database.updates))
+	}
+
+	if err := c.Recompute(context.Background(), true); err != nil {
+		t.Fatalf("Recompute() error = %v", err)
+	}
+	if len(database.ratings) != 4 || len(database.updates) != 4 {
+		t.Errorf("recompute skipping game without lineup left %d ratings and %d rating updates, want 4 of each", len(database.ratings), len(database.updates))
+	}
+}
+
+func TestCollectGamesPendingGames(t *testing.T) {
+	database := newFakeDatabase()
+	api := &fakePickupAPI{games: []tf2pickup.Result{testGame(1, "started", 0, 0), testGame(2, "started", 0, 0)}}
+	c := newTestCollector(t, database, api)
+
+	if err := c.CollectGames(context.Background(), 0, 10); err != nil {
+		t.Fatalf("CollectGames() error = %v", err)
+	}
+	if len(database.ratings) != 0 {
+		t.Fatalf("ongoing games created %d ratings", len(database.ratings))
+	}
+
+	// first game fails to load, second one has ended since previous run and a new game is played
+	api.games = []tf2pickup.Result{testGame(1, "started", 0, 0), testGame(2, "ended", 5, 0), testGame(3, "ended", 1, 1)}
+	api.failing = []string{"game-1"}
+
+	if err := c.CollectGames(context.Background(), 0, 10); err != nil {
+		t.Fatalf("CollectGames() error = %v", err)
+	}
+
+	for number, state := range map[int64]string{1: "started", 2: "ended", 3: "ended"} {
+		if database.games[number].State != state {
+			t.Errorf("game %d state = %q, want %q", number, database.games[number].State, state)
+		}
+	}
+	if len(database.updates) != 8 {
+		t.Errorf("logged %d rating updates, want 8", len(database.updates))
+	}
+}
@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"context"
+	"time"
+)
+
+// DecayRatings grows uncertainty of all pickup site ratings for the time players have been inactive until now,
+// so ratings reflect time passed even when no new games are played.
+func (c *Collector) DecayRatings(ctx context.Context) error {
+	return c.inflateUncertainty(ctx, c.db, nil, time.Now().UTC().Format(time.RFC3339))
+}
+
+// inflateUncertainty grows uncertainty of players' ratings up to the default uncertainty of collector's Rater.
+// If steamIDs is empty, ratings of all players are updated.
+func (c *Collector) inflateUncertainty(ctx context.Context, tx database, steamIDs []int64, ts string) error {
+	if c.uncertaintyGrowth <= 0 {
+		return nil
+	}
+
+	return tx.InflateUncertainty(ctx, c.pickupSite, steamIDs, ts, c.uncertaintyGrowth, c.rater.DefaultRating().UncertaintyValue)
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/samber/lo"
+)
+
+// eloRater is classic Elo applied to teams: team rating is an average of its players' ratings,
+// and every player of the team gets the same rating change. Uncertainty is not used and stays zero,
+// so conservative rating equals rating and uncertainty growth of inactive players never applies.
+type eloRater struct {
+	// k is the maximum rating change per game
+	k float64
+	// scale is the rating difference at which stronger team is expected to win 10 times more often
+	scale float64
+}
+
+func (eloRater) Name() string {
+	return AlgorithmElo
+}
+
+func (eloRater) DefaultRating() db.PlayerRating {
+	return db.PlayerRating{Rating: 15}
+}
+
+func (e eloRater) Rate(red, blu []db.PlayerRating, redScore, bluScore int64) ([]db.PlayerRating, []db.PlayerRating) {
+	diff := e.k * (actualScore(redScore, bluScore) - e.WinProbability(red, blu))
+
+	return shiftRatings(red, diff), shiftRatings(blu, -diff)
+}
+
+// WinProbability returns expected score of red team, ties count as half a win
+func (e eloRater) WinProbability(red, blu []db.PlayerRating) float64 {
+	return 1 / (1 + math.Pow(10, (averageRating(blu)-averageRating(red))/e.scale))
+}
+
+func averageRating(team []db.PlayerRating) float64 {
+	if len(team) == 0 {
+		return 0
+	}
+
+	return lo.SumBy(team, func(p db.PlayerRating) float64 { return p.Rating }) / float64(len(team))
+}
+
+func shiftRatings(team []db.PlayerRating, diff float64) []db.PlayerRating {
+	for i := range team {
+		team[i].Rating += diff
+	}
+
+	return team
+}
@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/samber/lo"
+)
+
+const (
+	// glicko2Scale converts between Glicko and Glicko-2 scales
+	glicko2Scale = 173.7178
+	// glicko2Center is default rating on Glicko scale
+	glicko2Center = 1500
+	// glicko2Epsilon is convergence tolerance of volatility iteration
+	glicko2Epsilon = 0.000001
+)
+
+// glicko2Rater is Glicko-2 applied to teams: every player is rated individually against
+// a single opponent built from the other team, with average skill and root mean square deviation
+// of its players. Rating deviation is stored as uncertainty.
+type glicko2Rater struct {
+	// tau constrains volatility change over time
+	tau float64
+}
+
+type glicko2Rating struct {
+	mu, phi, sigma float64
+}
+
+// glicko2Result is outcome of a single game against opponent, score is 1 for win, 0.5 for tie and 0 for loss
+type glicko2Result struct {
+	opponent glicko2Rating
+	score    float64
+}
+
+func (glicko2Rater) Name() string {
+	return AlgorithmGlicko2
+}
+
+func (glicko2Rater) DefaultRating() db.PlayerRating {
+	return db.PlayerRating{
+		Rating:           glicko2Center / 100,
+		UncertaintyValue: 3.5,
+		Volatility:       0.06,
+	}
+}
+
+func (g glicko2Rater) Rate(red, blu []db.PlayerRating, redScore, bluScore int64) ([]db.PlayerRating, []db.PlayerRating) {
+	redOpponent, bluOpponent := glicko2Opponent(blu), glicko2Opponent(red)
+	score := actualScore(redScore, bluScore)
+
+	for i, p := range red {
+		red[i] = g.update(p, glicko2Result{opponent: redOpponent, score: score})
+	}
+
+	for i, p := range blu {
+		blu[i] = g.update(p, glicko2Result{opponent: bluOpponent, score: 1 - score})
+	}
+
+	return red, blu
+}
+
+// WinProbability compares average skills of both teams, taking deviations of both teams into account
+func (glicko2Rater) WinProbability(red, blu []db.PlayerRating) float64 {
+	r, b := glicko2Opponent(red), glicko2Opponent(blu)
+
+	return 1 / (1 + math.Exp(-glicko2G(math.Hypot(r.phi, b.phi))*(r.mu-b.mu)))
+}
+
+// update rates single player after games against given opponents in one rating period
+func (g glicko2Rater) update(p db.PlayerRating, results ...glicko2Result) db.PlayerRating {
+	r := toGlicko2(p)
+
+	var vInv, improvement float64
+	for _, res := range results {
+		gPhi := glicko2G(res.opponent.phi)
+		expected := 1 / (1 + math.Exp(-gPhi*(r.mu-res.opponent.mu)))
+
+		vInv += gPhi * gPhi * expected * (1 - expected)
+		improvement += gPhi * (res.score - expected)
+	}
+
+	v := 1 / vInv
+	delta := v * improvement
+
+	sigma := g.volatility(r, v, delta)
+
+	phiStar := math.Sqrt(r.phi*r.phi + sigma*sigma)
+	phi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu := r.mu + phi*phi*improvement
+
+	p.Rating = (glicko2Scale*mu + glicko2Center) / 100
+	p.UncertaintyValue = glicko2Scale * phi / 100
+	p.Volatility = sigma
+
+	return p
+}
+
+// volatility finds new volatility with the Illinois algorithm, as described in Glicko-2 paper
+func (g glicko2Rater) volatility(r glicko2Rating, v, delta float64) float64 {
+	a := math.Log(r.sigma * r.sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := r.phi*r.phi + v + ex
+
+		return ex*(delta*delta-r.phi*r.phi-v-ex)/(2*d*d) - (x-a)/(g.tau*g.tau)
+	}
+
+	lower := a
+	var upper float64
+	if delta*delta > r.phi*r.phi+v {
+		upper = math.Log(delta*delta - r.phi*r.phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*g.tau) < 0 {
+			k++
+		}
+		upper = a - k*g.tau
+	}
+
+	fLower, fUpper := f(lower), f(upper)
+	for math.Abs(upper-lower) > glicko2Epsilon {
+		c := lower + (lower-upper)*fLower/(fUpper-fLower)
+		fc := f(c)
+
+		if fc*fUpper < 0 {
+			lower, fLower = upper, fUpper
+		} else {
+			fLower /= 2
+		}
+
+		upper, fUpper = c, fc
+	}
+
+	return math.Exp(lower / 2)
+}
+
+func glicko2Opponent(team []db.PlayerRating) glicko2Rating {
+	ratings := lo.Map(team, func(p db.PlayerRating, _ int) glicko2Rating { return toGlicko2(p) })
+	n := float64(len(ratings))
+
+	return glicko2Rating{
+		mu:  lo.SumBy(ratings, func(r glicko2Rating) float64 { return r.mu }) / n,
+		phi: math.Sqrt(lo.SumBy(ratings, func(r glicko2Rating) float64 { return r.phi * r.phi }) / n),
+	}
+}
+
+func toGlicko2(p db.PlayerRating) glicko2Rating {
+	return glicko2Rating{
+		mu:    (p.Rating*100 - glicko2Center) / glicko2Scale,
+		phi:   p.UncertaintyValue * 100 / glicko2Scale,
+		sigma: p.Volatility,
+	}
+}
+
+func glicko2G(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
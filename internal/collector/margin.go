@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+)
+
+const (
+	MarginCurveNone   = "none"
+	MarginCurveLinear = "linear"
+	MarginCurveLog    = "log"
+)
+
+// MarginCurves lists curves accepted by MarginWeighting
+var MarginCurves = []string{MarginCurveNone, MarginCurveLinear, MarginCurveLog}
+
+// MarginWeighting makes decisive games move ratings more than close ones by scaling
+// rating changes with a weight depending on score margin. Uncertainty changes are not scaled.
+type MarginWeighting struct {
+	// Curve is one of MarginCurves, empty value disables weighting
+	Curve string
+	// Cap is the maximum weight of the game
+	Cap float64
+}
+
+func (m MarginWeighting) Validate() error {
+	switch m.Curve {
+	case "", MarginCurveNone, MarginCurveLinear, MarginCurveLog:
+	default:
+		return fmt.Errorf("unknown margin curve %q", m.Curve)
+	}
+
+	if m.Cap < 1 {
+		return fmt.Errorf("margin weight cap must be at least 1, got %v", m.Cap)
+	}
+
+	return nil
+}
+
+// weight returns rating change multiplier for the game. Ties and one point wins have weight 1,
+// larger margins increase it with the curve: linearly (margin) or logarithmically (1 + ln(margin)).
+func (m MarginWeighting) weight(redScore, bluScore int64) float64 {
+	margin := math.Abs(float64(redScore - bluScore))
+	if margin <= 1 {
+		return 1
+	}
+
+	var w float64
+	switch m.Curve {
+	case MarginCurveLinear:
+		w = margin
+	case MarginCurveLog:
+		w = 1 + math.Log(margin)
+	default:
+		return 1
+	}
+
+	return math.Min(w, m.Cap)
+}
+
+// scaleRatingChanges scales rating changes from before to after by weight
+func scaleRatingChanges(before, after []db.PlayerRating, weight float64) []db.PlayerRating {
+	for i := range after {
+		after[i].Rating = before[i].Rating + weight*(after[i].Rating-before[i].Rating)
+	}
+
+	return after
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"math"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+)
+
+func TestMarginWeightingWeight(t *testing.T) {
+	tests := []struct {
+		name     string
+		margin   MarginWeighting
+		red, blu int64
+		want     float64
+	}{
+		{"tie", MarginWeighting{Curve: MarginCurveLinear, Cap: 3}, 2, 2, 1},
+		{"scoreless tie", MarginWeighting{Curve: MarginCurveLog, Cap: 3}, 0, 0, 1},
+		{"one point red win", MarginWeighting{Curve: MarginCurveLinear, Cap: 3}, 3, 2, 1},
+		{"one point blu win", MarginWeighting{Curve: MarginCurveLog, Cap: 3}, 2, 3, 1},
+		{"linear two points", MarginWeighting{Curve: MarginCurveLinear, Cap: 3}, 3, 1, 2},
+		{"log two points", MarginWeighting{Curve: MarginCurveLog, Cap: 3}, 1, 3, 1 + math.Log(2)},
+		{"linear shutout capped", MarginWeighting{Curve: MarginCurveLinear, Cap: 2}, 5, 0, 2},
+		{"log shutout capped", MarginWeighting{Curve: MarginCurveLog, Cap: 2}, 0, 5, 2},
+		{"log shutout under cap", MarginWeighting{Curve: MarginCurveLog, Cap: 3}, 5, 0, 1 + math.Log(5)},
+		{"none shutout", MarginWeighting{Curve: MarginCurveNone, Cap: 2}, 5, 0, 1},
+		{"empty curve shutout", MarginWeighting{Cap: 2}, 0, 5, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.margin.weight(tt.red, tt.blu); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("weight(%d, %d) = %v, want %v", tt.red, tt.blu, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarginWeightingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		margin  MarginWeighting
+		wantErr bool
+	}{
+		{"empty curve", MarginWeighting{Cap: 1}, false},
+		{"none", MarginWeighting{Curve: MarginCurveNone, Cap: 2}, false},
+		{"linear", MarginWeighting{Curve: MarginCurveLinear, Cap: 2}, false},
+		{"log", MarginWeighting{Curve: MarginCurveLog, Cap: 1.5}, false},
+		{"unknown curve", MarginWeighting{Curve: "exp", Cap: 2}, true},
+		{"cap below one", MarginWeighting{Curve: MarginCurveLinear, Cap: 0.5}, true},
+		{"zero cap", MarginWeighting{Curve: MarginCurveNone}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.margin.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScaleRatingChanges(t *testing.T) {
+	before := []db.PlayerRating{{Rating: 25, UncertaintyValue: 8}, {Rating: 30, UncertaintyValue: 5}}
+	after := []db.PlayerRating{{Rating: 26, UncertaintyValue: 7}, {Rating: 29.5, UncertaintyValue: 4.5}}
+
+	got := scaleRatingChanges(before, after, 2)
+
+	want := []db.PlayerRating{{Rating: 27, UncertaintyValue: 7}, {Rating: 29, UncertaintyValue: 4.5}}
+	for i := range want {
+		if got[i].Rating != want[i].Rating || got[i].UncertaintyValue != want[i].UncertaintyValue {
+			t.Errorf("player %d rating = %v ± %v, want %v ± %v", i, got[i].Rating, got[i].UncertaintyValue, want[i].Rating, want[i].UncertaintyValue)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/eullerpereira94/openskill"
+)
+
+// openSkillRater uses OpenSkill's Plackett-Luce model
+type openSkillRater struct{}
+
+func (openSkillRater) Name() string {
+	return AlgorithmOpenSkill
+}
+
+func (openSkillRater) DefaultRating() db.PlayerRating {
+	const ratingValue = 16.0
+
+	return db.PlayerRating{
+		Rating:           ratingValue,
+		UncertaintyValue: ratingValue / 3.0,
+	}
+}
+
+func (openSkillRater) Rate(red, blu []db.PlayerRating, redScore, bluScore int64) ([]db.PlayerRating, []db.PlayerRating) {
+	redRatings := playerRatingsToOpenSkillTeam(red)
+	bluRatings := playerRatingsToOpenSkillTeam(blu)
+
+	teams := openskill.Rate([]openskill.Team{redRatings, bluRatings}, openskill.Options{Scores: []int64{redScore, bluScore}})
+
+	return applyOpenSkillRatings(red, teams[0]), applyOpenSkillRatings(blu, teams[1])
+}
+
+func (openSkillRater) WinProbability(red, blu []db.PlayerRating) float64 {
+	teams := []openskill.Team{playerRatingsToOpenSkillTeam(red), playerRatingsToOpenSkillTeam(blu)}
+
+	return openskill.PredictWin(teams, nil)[0]
+}
+
+func applyOpenSkillRatings(team []db.PlayerRating, ratings openskill.Team) []db.PlayerRating {
+	for i, p := range team {
+		p.Rating = ratings[i].AveragePlayerSkill
+		p.UncertaintyValue = ratings[i].SkillUncertaintyDegree
+		team[i] = p
+	}
+
+	return team
+}
+
+func playerRatingsToOpenSkillTeam(team []db.PlayerRating) openskill.Team {
+	var ratings = make([]*openskill.Rating, len(team))
+	for i, p := range team {
+		ratings[i] = openskill.NewRating(&openskill.NewRatingParams{
+			AveragePlayerSkill:     p.Rating,
+			SkillUncertaintyDegree: p.UncertaintyValue,
+		}, nil)
+	}
+
+	return openskill.NewTeam(ratings...)
+}
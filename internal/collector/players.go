@@ -1,10 +1,16 @@
 package collector
 
 import (
+	"slices"
+
 	"github.com/condensedtea/pickup-ratings/internal/db"
 	"github.com/condensedtea/pickup-ratings/internal/tf2pickup"
+	"github.com/samber/lo"
 )
 
+// classOrder is the order of TF2 classes used for pickup site class lists
+var classOrder = []string{"scout", "soldier", "pyro", "demoman", "heavy", "engineer", "medic", "sniper", "spy"}
+
 type player struct {
 	name      string
 	avatarURL string
@@ -18,31 +24,47 @@ type playerSet struct {
 	steamIDs       []int64
 }
 
-func newPlayerSet(slots []tf2pickup.Slot) playerSet {
-	steamIDMapping := make(map[int64]player, len(slots))
-	steamIDs := make([]int64, len(slots))
+func newPlayerSet(players []player) playerSet {
+	steamIDMapping := make(map[int64]player, len(players))
+	steamIDs := make([]int64, len(players))
+
+	for i, p := range players {
+		steamIDs[i] = p.steamID
+		steamIDMapping[p.steamID] = p
+	}
+
+	return playerSet{
+		steamIDMapping: steamIDMapping,
+		steamIDs:       steamIDs,
+	}
+}
 
-	for i, slot := range slots {
-		steamID := slot.Player.SteamId
-		steamIDs[i] = steamID
-		steamIDMapping[steamID] = player{
+func playersFromSlots(slots []tf2pickup.Slot) []player {
+	return lo.Map(slots, func(slot tf2pickup.Slot, _ int) player {
+		return player{
 			name:      slot.Player.Name,
 			avatarURL: slot.Player.Avatar.Small,
 			steamID:   slot.Player.SteamId,
 			team:      slot.Team,
 			class:     slot.GameClass,
 		}
-	}
+	})
+}
 
-	return playerSet{
-		steamIDMapping: steamIDMapping,
-		steamIDs:       steamIDs,
-	}
+// playersFromLineup converts stored game lineup to players, names and avatars are not stored with it
+func playersFromLineup(lineup []db.GamePlayer) []player {
+	return lo.Map(lineup, func(gp db.GamePlayer, _ int) player {
+		return player{
+			steamID: gp.SteamID,
+			team:    gp.Team,
+			class:   gp.Class,
+		}
+	})
 }
 
 // filterRatingsByClass accepts slice of any ratings with given steamIDs and filters them based on playerSet player's classes
 func (ps playerSet) filterRatingsByClass(ratings []db.PlayerRating) []db.PlayerRating {
-	var playerRatings = make([]db.PlayerRating, len(ps.steamIDs))
+	var playerRatings = make([]db.PlayerRating, 0, len(ps.steamIDs))
 	for _, steamIDRating := range ratings {
 		p := ps.steamIDMapping[steamIDRating.SteamID]
 
@@ -62,13 +84,44 @@ func (ps playerSet) bySteamID(id int64) player {
 	return ps.steamIDMapping[id]
 }
 
-func defaultRating(p player) db.PlayerRating {
-	const ratingValue = 16.0
+// classes returns unique classes of the players, known TF2 classes come first in their usual order
+func (ps playerSet) classes() []string {
+	classes := lo.Uniq(lo.Map(ps.steamIDs, func(steamID int64, _ int) string {
+		return ps.steamIDMapping[steamID].class
+	}))
+
+	slices.SortStableFunc(classes, func(a, b string) int {
+		return classIndex(a) - classIndex(b)
+	})
 
-	return db.PlayerRating{
-		SteamID:          p.steamID,
-		Class:            p.class,
-		Rating:           ratingValue,
-		UncertaintyValue: ratingValue / 3.0,
+	return classes
+}
+
+func classIndex(class string) int {
+	if i := slices.Index(classOrder, class); i >= 0 {
+		return i
 	}
+
+	return len(classOrder)
+}
+
+func (ps playerSet) lineup(gameID int64) []db.GamePlayer {
+	return lo.Map(ps.steamIDs, func(steamID int64, _ int) db.GamePlayer {
+		p := ps.steamIDMapping[steamID]
+		return db.GamePlayer{
+			GameID:  gameID,
+			SteamID: p.steamID,
+			Team:    p.team,
+			Class:   p.class,
+		}
+	})
+}
+
+func defaultRating(rater Rater, p player) db.PlayerRating {
+	r := rater.DefaultRating()
+	r.SteamID = p.steamID
+	r.Class = p.class
+	r.Algorithm = rater.Name()
+
+	return r
 }
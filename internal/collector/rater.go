@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+)
+
+// Rater is a rating algorithm. All raters keep ratings in the same units as OpenSkill does,
+// which is a hundredth of the value displayed on the website.
+type Rater interface {
+	// Name is stored with every leaderboard rated by Rater
+	Name() string
+	// DefaultRating returns rating values of player without games
+	DefaultRating() db.PlayerRating
+	// Rate returns updated ratings of both teams in the same order. Only rating values
+	// (Rating, UncertaintyValue and Volatility) are changed.
+	Rate(red, blu []db.PlayerRating, redScore, bluScore int64) ([]db.PlayerRating, []db.PlayerRating)
+	// WinProbability returns probability of red team winning against blu team
+	WinProbability(red, blu []db.PlayerRating) float64
+}
+
+const (
+	AlgorithmOpenSkill = "openskill"
+	AlgorithmElo       = "elo"
+	AlgorithmGlicko2   = "glicko2"
+)
+
+// Algorithms lists names accepted by NewRater
+var Algorithms = []string{AlgorithmOpenSkill, AlgorithmElo, AlgorithmGlicko2}
+
+func NewRater(algorithm string) (Rater, error) {
+	switch algorithm {
+	case AlgorithmOpenSkill:
+		return openSkillRater{}, nil
+	case AlgorithmElo:
+		return eloRater{k: 0.32, scale: 4}, nil
+	case AlgorithmGlicko2:
+		return glicko2Rater{tau: 0.5}, nil
+	default:
+		return nil, fmt.Errorf("unknown rating algorithm %q", algorithm)
+	}
+}
+
+// actualScore returns game outcome from red team's point of view: 1 for win, 0.5 for tie and 0 for loss
+func actualScore(redScore, bluScore int64) float64 {
+	switch {
+	case redScore > bluScore:
+		return 1
+	case redScore < bluScore:
+		return 0
+	default:
+		return 0.5
+	}
+}
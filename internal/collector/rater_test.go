@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/samber/lo"
+)
+
+// TestGlicko2PaperExample checks the worked example from Glickman's "Example of the Glicko-2 system"
+func TestGlicko2PaperExample(t *testing.T) {
+	g := glicko2Rater{tau: 0.5}
+	player := db.PlayerRating{Rating: 15, UncertaintyValue: 2, Volatility: 0.06}
+
+	got := g.update(player,
+		glicko2Result{opponent: toGlicko2(db.PlayerRating{Rating: 14, UncertaintyValue: 0.3}), score: 1},
+		glicko2Result{opponent: toGlicko2(db.PlayerRating{Rating: 15.5, UncertaintyValue: 1}), score: 0},
+		glicko2Result{opponent: toGlicko2(db.PlayerRating{Rating: 17, UncertaintyValue: 3}), score: 0},
+	)
+
+	if math.Abs(got.Rating*100-1464.06) > 0.01 {
+		t.Errorf("rating = %v, want 1464.06", got.Rating*100)
+	}
+	if math.Abs(got.UncertaintyValue*100-151.52) > 0.01 {
+		t.Errorf("rating deviation = %v, want 151.52", got.UncertaintyValue*100)
+	}
+	if math.Abs(got.Volatility-0.05999) > 0.00001 {
+		t.Errorf("volatility = %v, want 0.05999", got.Volatility)
+	}
+}
+
+func TestEloRate(t *testing.T) {
+	e := eloRater{k: 0.32, scale: 4}
+
+	tests := []struct {
+		name               string
+		red, blu           []float64
+		redScore, bluScore int64
+	}{
+		{"equal teams red win", []float64{15, 15}, []float64{15, 15}, 3, 0},
+		{"equal teams blu win", []float64{15, 15}, []float64{15, 15}, 1, 2},
+		{"stronger red win", []float64{20, 16}, []float64{14, 13}, 5, 1},
+		{"weaker red win", []float64{12, 11}, []float64{18, 17}, 2, 1},
+		{"unequal teams tie", []float64{18, 16}, []float64{12, 15}, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			red, blu := eloTeam(tt.red), eloTeam(tt.blu)
+			gotRed, gotBlu := e.Rate(slices.Clone(red), slices.Clone(blu), tt.redScore, tt.bluScore)
+
+			redChange, bluChange := averageRating(gotRed)-averageRating(red), averageRating(gotBlu)-averageRating(blu)
+			if math.Abs(redChange+bluChange) > 1e-9 {
+				t.Errorf("red changed by %v and blu by %v, want zero sum", redChange, bluChange)
+			}
+			if redChange == 0 {
+				t.Errorf("rating didn't change")
+			}
+		})
+	}
+
+	t.Run("equal teams tie", func(t *testing.T) {
+		red, blu := eloTeam([]float64{15, 17}), eloTeam([]float64{17, 15})
+		gotRed, gotBlu := e.Rate(slices.Clone(red), slices.Clone(blu), 1, 1)
+
+		want := append(red, blu...)
+		for i, p := range append(gotRed, gotBlu...) {
+			if p.Rating != want[i].Rating {
+				t.Errorf("player %d rating = %v, want %v", i, p.Rating, want[i].Rating)
+			}
+		}
+	})
+}
+
+func eloTeam(ratings []float64) []db.PlayerRating {
+	return lo.Map(ratings, func(r float64, _ int) db.PlayerRating { return db.PlayerRating{Rating: r} })
+}
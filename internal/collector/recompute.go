@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slog"
+)
+
+// Recompute drops all ratings of pickup site and rebuilds them from scratch with collector's Rater
+// by replaying stored games with their lineups in game order. Pickup API is not used.
+// Ended games collected before lineups were stored can't be replayed, so unless skipMissing is set
+// Recompute fails without changing anything if there are any.
+func (c *Collector) Recompute(ctx context.Context, skipMissing bool) error {
+	return c.db.WithTx(ctx, func(tx database) error {
+		games, err := tx.GetGames(ctx, c.pickupSite)
+		if err != nil {
+			return err
+		}
+
+		gamePlayers, err := tx.GetGamePlayers(ctx, c.pickupSite)
+		if err != nil {
+			return err
+		}
+
+		lineups := lo.GroupBy(gamePlayers, func(gp db.GamePlayer) int64 {
+			return gp.GameID
+		})
+
+		games = lo.Filter(games, func(game db.Game, _ int) bool {
+			return game.State == "ended"
+		})
+
+		missing := lo.CountBy(games, func(game db.Game) bool {
+			_, ok := lineups[game.ID]
+			return !ok
+		})
+
+		if missing > 0 && !skipMissing {
+			return fmt.Errorf("%d of %d ended games have no stored lineup and would lose their ratings", missing, len(games))
+		}
+
+		if err = tx.DeletePlayerRatings(ctx, c.pickupSite); err != nil {
+			return err
+		}
+
+		for _, game := range games {
+			lineup, ok := lineups[game.ID]
+			if !ok {
+				continue
+			}
+
+			slog.Info("replaying game", "number", game.ID)
+			if err = c.rateGame(ctx, tx, game, newPlayerSet(playersFromLineup(lineup))); err != nil {
+				return fmt.Errorf("replaying game %d: %w", game.ID, err)
+			}
+		}
+
+		if missing > 0 {
+			slog.Warn("skipped games without stored lineup", "count", missing)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"slices"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+)
+
+const (
+	// sidePriorGames is the number of virtual even games added to side statistics of every map,
+	// so side advantage of rarely played maps stays close to zero
+	sidePriorGames = 20
+	// sideMinGames is the number of games that must be played on the map before side advantage is applied
+	sideMinGames = 20
+	// maxSideOffset limits rating offset of side advantage
+	maxSideOffset = 20
+)
+
+// sideAdvantage returns log-odds of red side winning against equal blu team on the map of the game.
+// It is estimated from results of games played on the map before the game.
+func (c *Collector) sideAdvantage(ctx context.Context, tx database, game db.Game) (float64, error) {
+	stats, err := tx.GetMapStatsBefore(ctx, c.pickupSite, game.Map, game.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return mapSideAdvantage(stats), nil
+}
+
+// mapSideAdvantage returns log-odds of red side winning on the map with given statistics, ties count as half a win.
+// Maps with fewer than sideMinGames games have no side advantage.
+func mapSideAdvantage(stats db.MapStats) float64 {
+	if stats.Games < sideMinGames {
+		return 0
+	}
+
+	redScore := (float64(stats.RedWins) + float64(stats.Ties)/2 + sidePriorGames/2) / (float64(stats.Games) + sidePriorGames)
+
+	return logit(redScore)
+}
+
+// sideOffset returns rating offset which makes win probability predicted by rater include side advantage,
+// when half of it is added to red players' ratings and half is subtracted from blu players' ratings.
+// Raters have no closed form for it, so it is found with bisection.
+func sideOffset(rater Rater, red, blu []db.PlayerRating, advantage float64) float64 {
+	if advantage == 0 {
+		return 0
+	}
+
+	target := logit(rater.WinProbability(red, blu)) + advantage
+
+	var low, high float64 = -maxSideOffset, maxSideOffset
+	for i := 0; i < 50; i++ {
+		offset := (low + high) / 2
+
+		p := rater.WinProbability(shiftRatings(slices.Clone(red), offset/2), shiftRatings(slices.Clone(blu), -offset/2))
+		if logit(p) < target {
+			low = offset
+		} else {
+			high = offset
+		}
+	}
+
+	return (low + high) / 2
+}
+
+// logit returns log-odds of probability p, probabilities are clamped so that certain outcomes stay finite
+func logit(p float64) float64 {
+	const eps = 1e-9
+
+	p = math.Min(math.Max(p, eps), 1-eps)
+
+	return math.Log(p / (1 - p))
+}
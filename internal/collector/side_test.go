@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+)
+
+func TestSideOffset(t *testing.T) {
+	for _, algorithm := range Algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			rater, err := NewRater(algorithm)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stronger, weaker := rater.DefaultRating(), rater.DefaultRating()
+			stronger.Rating += 1
+			red, blu := []db.PlayerRating{stronger, weaker}, []db.PlayerRating{weaker, weaker}
+
+			for _, advantage := range []float64{-0.5, -0.1, 0.1, 0.5} {
+				offset := sideOffset(rater, red, blu, advantage)
+
+				got := logit(rater.WinProbability(shiftRatings(slices.Clone(red), offset/2), shiftRatings(slices.Clone(blu), -offset/2)))
+				if want := logit(rater.WinProbability(red, blu)) + advantage; math.Abs(got-want) > 1e-6 {
+					t.Errorf("advantage %v: offset %v gives log-odds %v, want %v", advantage, offset, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSideOffsetWithoutEnoughMapGames(t *testing.T) {
+	rater, _ := NewRater(AlgorithmOpenSkill)
+	red, blu := []db.PlayerRating{rater.DefaultRating()}, []db.PlayerRating{rater.DefaultRating()}
+
+	for _, stats := range []db.MapStats{{}, {Games: sideMinGames - 1, RedWins: sideMinGames - 1}} {
+		if got := sideOffset(rater, red, blu, mapSideAdvantage(stats)); got != 0 {
+			t.Errorf("offset with %d red wins of %d games = %v, want 0", stats.RedWins, stats.Games, got)
+		}
+	}
+}
+
+func TestSideOffsetOneSidedMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats db.MapStats
+	}{
+		{"red always wins", db.MapStats{Games: 100000, RedWins: 100000}},
+		{"blu always wins", db.MapStats{Games: 100000, BluWins: 100000}},
+	}
+
+	for _, algorithm := range Algorithms {
+		rater, err := NewRater(algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		red, blu := []db.PlayerRating{rater.DefaultRating()}, []db.PlayerRating{rater.DefaultRating()}
+
+		for _, tt := range tests {
+			t.Run(algorithm+" "+tt.name, func(t *testing.T) {
+				advantage := mapSideAdvantage(tt.stats)
+				if math.IsNaN(advantage) || math.IsInf(advantage, 0) {
+					t.Fatalf("advantage = %v, want finite", advantage)
+				}
+
+				// certain outcomes are clamped by logit, which still needs the largest offset
+				for _, adv := range []float64{advantage, logit(0), logit(1)} {
+					offset := sideOffset(rater, red, blu, adv)
+					if math.IsNaN(offset) || math.Abs(offset) > maxSideOffset {
+						t.Errorf("offset with advantage %v = %v, want finite within ±%d", adv, offset, maxSideOffset)
+					}
+
+					p := rater.WinProbability(shiftRatings(slices.Clone(red), offset/2), shiftRatings(slices.Clone(blu), -offset/2))
+					if math.IsNaN(p) || p < 0 || p > 1 {
+						t.Errorf("win probability with offset %v = %v", offset, p)
+					}
+				}
+			})
+		}
+	}
+}
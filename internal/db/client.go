@@ -3,14 +3,14 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/samber/lo"
 )
 
-var classes = []string{"medic", "demoman", "soldier", "scout"}
-
 type Player struct {
 	Name       string
 	AvatarURL  string
@@ -26,6 +26,16 @@ type Game struct {
 	RedScore   int64
 	Ts         string
 	PickupID   string
+	State      string
+	// RedWinProbability is nil until the game is rated
+	RedWinProbability *float64
+}
+
+type GamePlayer struct {
+	GameID  int64
+	SteamID int64
+	Team    string
+	Class   string
 }
 
 type PlayerRating struct {
@@ -34,29 +44,47 @@ type PlayerRating struct {
 
 	Rating           float64
 	UncertaintyValue float64
+	Volatility       float64
 	Result           string
 	GamesPlayed      int64
 	GamesTied        int64
 	GamesWon         int64
 
-	Class string
-	Team  string
+	Algorithm string
+	Class     string
+	Team      string
 }
 
+// RatingUpdate is a rating of player after the game. Values before the game and uncertainty
+// are nil for some updates logged before they were stored in history.
 type RatingUpdate struct {
-	GameID   int64
-	PickupID string
-	GameMap  string
-	Rating   float64
-	Result   string
-	RedScore int64
-	BluScore int64
-	Date     string
-	Time     string
+	GameID            int64
+	PickupID          string
+	GameMap           string
+	RatingBefore      *float64
+	Rating            float64
+	UncertaintyBefore *float64
+	Uncertainty       *float64
+	Result            string
+	RedScore          int64
+	BluScore          int64
+	Date              string
+	Time              string
+	Ts                string
+}
+
+// conn is implemented by both *pgxpool.Pool and pgx.Tx, so Client methods
+// work the same way inside and outside of a transaction.
+type conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 type Client struct {
-	pool *pgxpool.Pool
+	conn conn
 }
 
 func NewClient(ctx context.Context, dsn string) (*Client, error) {
@@ -65,14 +93,35 @@ func NewClient(ctx context.Context, dsn string) (*Client, error) {
 		return nil, fmt.Errorf("failed to create client: %v", err)
 	}
 
-	return &Client{pool: pool}, nil
+	return &Client{conn: pool}, nil
+}
+
+// WithTx runs fn in a transaction. Client passed to fn is bound to that transaction,
+// which is committed if fn returns nil and rolled back otherwise.
+func (c *Client) WithTx(ctx context.Context, fn func(tx *Client) error) error {
+	tx, err := c.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("WithTx: begin: %w", err)
+	}
+	// no-op if transaction is already committed
+	defer tx.Rollback(ctx)
+
+	if err = fn(&Client{conn: tx}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("WithTx: commit: %w", err)
+	}
+
+	return nil
 }
 
 func (c *Client) GetLastGameID(ctx context.Context, pickupSite string) (int, error) {
 	const query = `select game_id from game_history where pickup_site = $1 order by game_id desc limit 1`
 
 	var gameID int
-	if err := c.pool.QueryRow(ctx, query, pickupSite).Scan(&gameID); err != nil {
+	if err := c.conn.QueryRow(ctx, query, pickupSite).Scan(&gameID); err != nil {
 		return 0, fmt.Errorf("GetLastGameID: %w", err)
 	}
 
@@ -84,7 +133,7 @@ func (c *Client) GetUnknownSteamIDs(ctx context.Context, steamIDs []int64, picku
 		select steam_id from unnest($1::bigint[]) as steam_ids(steam_id)
 		where not exists(select 1 from players p where p.steam_id = steam_ids.steam_id and pickup_site = $2)`
 
-	rows, err := c.pool.Query(ctx, query, steamIDs, pickupSite)
+	rows, err := c.conn.Query(ctx, query, steamIDs, pickupSite)
 	if err != nil {
 		return nil, fmt.Errorf("GetUnknownSteamIDs: %w", err)
 	}
@@ -94,18 +143,16 @@ func (c *Client) GetUnknownSteamIDs(ctx context.Context, steamIDs []int64, picku
 
 func (c *Client) CreatePlayerRatings(ctx context.Context, ratings []PlayerRating, pickupSite string) error {
 	const query = `
-			insert into player_leaderboard(pickup_site, player_steam_id, player_class, rating, uncertainty_value)
-			values ($1, $2, $3, $4, $5)`
+			insert into player_leaderboard(pickup_site, player_steam_id, player_class, rating, uncertainty_value, volatility, algorithm)
+			values ($1, $2, $3, $4, $5, $6, $7)`
 
 	b := &pgx.Batch{}
 
 	for _, r := range ratings {
-		for _, class := range classes {
-			b.Queue(query, pickupSite, r.SteamID, class, r.Rating, r.UncertaintyValue)
-		}
+		b.Queue(query, pickupSite, r.SteamID, r.Class, r.Rating, r.UncertaintyValue, r.Volatility, r.Algorithm)
 	}
 
-	br := c.pool.SendBatch(ctx, b)
+	br := c.conn.SendBatch(ctx, b)
 	defer br.Close()
 
 	for i := 0; i < b.Len(); i++ {
@@ -127,7 +174,7 @@ func (c *Client) CreatePlayersBatch(ctx context.Context, players []Player, picku
 		b.Queue(query, player.Name, player.AvatarURL, player.SteamID, pickupSite)
 	}
 
-	br := c.pool.SendBatch(ctx, b)
+	br := c.conn.SendBatch(ctx, b)
 	defer br.Close()
 
 	for i := 0; i < b.Len(); i++ {
@@ -140,11 +187,18 @@ func (c *Client) CreatePlayersBatch(ctx context.Context, players []Player, picku
 	return nil
 }
 
+// SaveGame creates game or updates already saved one, e.g. when pending game has ended
 func (c *Client) SaveGame(ctx context.Context, game Game) error {
-	const query = `insert into game_history(game_id, game_map, pickup_site, red_score, blu_score, ts, pickup_id)
-					values ($1, $2, $3, $4, $5, $6, $7)`
-
-	_, err := c.pool.Exec(ctx, query, game.ID, game.Map, game.PickupSite, game.RedScore, game.BluScore, game.Ts, game.PickupID)
+	const query = `insert into game_history(game_id, game_map, pickup_site, red_score, blu_score, ts, pickup_id, state)
+					values ($1, $2, $3, $4, $5, $6, $7, $8)
+					on conflict (game_id, pickup_site) do update set
+						game_map = excluded.game_map,
+						red_score = excluded.red_score,
+						blu_score = excluded.blu_score,
+						ts = excluded.ts,
+						state = excluded.state`
+
+	_, err := c.conn.Exec(ctx, query, game.ID, game.Map, game.PickupSite, game.RedScore, game.BluScore, game.Ts, game.PickupID, game.State)
 	if err != nil {
 		return fmt.Errorf("SaveGame: %w", err)
 	}
@@ -152,6 +206,176 @@ func (c *Client) SaveGame(ctx context.Context, game Game) error {
 	return nil
 }
 
+func (c *Client) GetGames(ctx context.Context, pickupSite string) ([]Game, error) {
+	const query = `
+		select game_id, game_map, pickup_site, blu_score, red_score, ts::text, pickup_id, state, red_win_probability
+		from game_history
+		where pickup_site = $1
+		order by game_id`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite)
+	if err != nil {
+		return nil, fmt.Errorf("GetGames: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Game])
+}
+
+func (c *Client) SetGameWinProbability(ctx context.Context, pickupSite string, gameID int64, redWinProbability float64) error {
+	const query = `update game_history set red_win_probability = $3 where pickup_site = $1 and game_id = $2`
+
+	if _, err := c.conn.Exec(ctx, query, pickupSite, gameID, redWinProbability); err != nil {
+		return fmt.Errorf("SetGameWinProbability: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingGames returns games which were not finished when they were saved
+func (c *Client) GetPendingGames(ctx context.Context, pickupSite string, states []string) ([]Game, error) {
+	const query = `
+		select game_id, game_map, pickup_site, blu_score, red_score, ts::text, pickup_id, state, red_win_probability
+		from game_history
+		where pickup_site = $1 and state = any($2::text[])
+		order by game_id`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, states)
+	if err != nil {
+		return nil, fmt.Errorf("GetPendingGames: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Game])
+}
+
+// SaveGamePlayers replaces previously stored lineup of the game, if any
+func (c *Client) SaveGamePlayers(ctx context.Context, gameID int64, pickupSite string, players []GamePlayer) error {
+	const query = `insert into game_players(game_id, pickup_site, steam_id, team, game_class) values ($1, $2, $3, $4, $5)`
+
+	b := &pgx.Batch{}
+	b.Queue(`delete from game_players where game_id = $1 and pickup_site = $2`, gameID, pickupSite)
+
+	for _, p := range players {
+		b.Queue(query, gameID, pickupSite, p.SteamID, p.Team, p.Class)
+	}
+
+	br := c.conn.SendBatch(ctx, b)
+	defer br.Close()
+
+	for i := 0; i < b.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("SaveGamePlayers: %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// GetGamePlayers returns lineups of all games stored for pickup site
+func (c *Client) GetGamePlayers(ctx context.Context, pickupSite string) ([]GamePlayer, error) {
+	const query = `
+		select game_id, steam_id, team, game_class
+		from game_players
+		where pickup_site = $1
+		order by game_id`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite)
+	if err != nil {
+		return nil, fmt.Errorf("GetGamePlayers: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[GamePlayer])
+}
+
+func (c *Client) GetGame(ctx context.Context, pickupSite string, gameID int64) (Game, error) {
+	const query = `
+		select game_id, game_map, pickup_site, blu_score, red_score, ts::text, pickup_id, state, red_win_probability
+		from game_history
+		where pickup_site = $1 and game_id = $2`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, gameID)
+	if err != nil {
+		return Game{}, fmt.Errorf("GetGame: %w", err)
+	}
+
+	return pgx.CollectOneRow(rows, pgx.RowToStructByPos[Game])
+}
+
+type LineupPlayer struct {
+	SteamID int64
+	Name    string
+	Team    string
+	Class   string
+}
+
+// GetGameLineup returns players of the game with their names, if they are known
+func (c *Client) GetGameLineup(ctx context.Context, pickupSite string, gameID int64) ([]LineupPlayer, error) {
+	const query = `
+		select gp.steam_id, coalesce(p.name, ''), gp.team, gp.game_class
+		from game_players gp
+		left join players p on p.steam_id = gp.steam_id and p.pickup_site = gp.pickup_site
+		where gp.pickup_site = $1 and gp.game_id = $2
+		order by gp.team, gp.game_class`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("GetGameLineup: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[LineupPlayer])
+}
+
+type GameRating struct {
+	SteamID   int64
+	Name      string
+	AvatarURL string
+	Team      string
+	Class     string
+	// RatingBefore is nil for some games logged before it was stored in history
+	RatingBefore *float64
+	// RatingAfter is nil if the game was not rated
+	RatingAfter *float64
+}
+
+// GetGameRatings returns lineup of the game with players' ratings before and after it
+func (c *Client) GetGameRatings(ctx context.Context, pickupSite string, gameID int64) ([]GameRating, error) {
+	const query = `
+		select
+			gp.steam_id,
+			coalesce(p.name, ''),
+			coalesce(p.avatar_url, ''),
+			gp.team,
+			gp.game_class,
+			rh.rating_before,
+			rh.rating_value
+		from game_players gp
+		left join players p on p.steam_id = gp.steam_id and p.pickup_site = gp.pickup_site
+		left join player_leaderboard pl on pl.player_steam_id = gp.steam_id
+			and pl.player_class = gp.game_class
+			and pl.pickup_site = gp.pickup_site
+		left join player_rating_history rh on rh.leaderboard_id = pl.id and rh.game_id = gp.game_id
+		where gp.pickup_site = $1 and gp.game_id = $2`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("GetGameRatings: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[GameRating])
+}
+
+// DeletePlayerRatings removes all leaderboards and rating history of pickup site
+func (c *Client) DeletePlayerRatings(ctx context.Context, pickupSite string) error {
+	if _, err := c.conn.Exec(ctx, `delete from player_rating_history where pickup_site = $1`, pickupSite); err != nil {
+		return fmt.Errorf("DeletePlayerRatings: deleting history: %w", err)
+	}
+
+	if _, err := c.conn.Exec(ctx, `delete from player_leaderboard where pickup_site = $1`, pickupSite); err != nil {
+		return fmt.Errorf("DeletePlayerRatings: deleting leaderboard: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int64, pickupSite string) ([]PlayerRating, error) {
 	const query = `
 		select
@@ -159,14 +383,16 @@ func (c *Client) GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int
 		    player_steam_id,
 		    rating,
 		    uncertainty_value,
+		    volatility,
 		    player_class,
 		    games_played,
 			games_tied,
-			games_won
+			games_won,
+			algorithm
 		from player_leaderboard
 		where pickup_site = $1 and player_steam_id = any($2::bigint[])`
 
-	rows, err := c.pool.Query(ctx, query, pickupSite, steamIDs)
+	rows, err := c.conn.Query(ctx, query, pickupSite, steamIDs)
 	if err != nil {
 		return nil, fmt.Errorf("GetPlayerRatingsForSteamIDs: %w", err)
 	}
@@ -176,10 +402,12 @@ func (c *Client) GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int
 		PlayerSteamID    int64
 		Rating           float64
 		UncertaintyValue float64
+		Volatility       float64
 		PlayerClass      string
 		GamesPlayed      int64
 		GamesTied        int64
 		GamesWon         int64
+		Algorithm        string
 	}
 
 	results, err := pgx.CollectRows(rows, pgx.RowToStructByPos[result])
@@ -193,24 +421,35 @@ func (c *Client) GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int
 			SteamID:          r.PlayerSteamID,
 			Rating:           r.Rating,
 			UncertaintyValue: r.UncertaintyValue,
+			Volatility:       r.Volatility,
 			Class:            r.PlayerClass,
 			GamesPlayed:      r.GamesPlayed,
 			GamesTied:        r.GamesTied,
 			GamesWon:         r.GamesWon,
+			Algorithm:        r.Algorithm,
 		}
 	}), nil
 }
 
-func (c *Client) LogRatingUpdates(ctx context.Context, gameID int64, pickupSite string, ratings []PlayerRating, ts string) error {
-	const query = `insert into player_rating_history(game_id, pickup_site, leaderboard_id, rating_value, result, ts) values ($1, $2, $3, $4, $5, $6)`
+// LogRatingUpdates saves rating changes of the game, before contains ratings of the same players in the same order as ratings
+func (c *Client) LogRatingUpdates(ctx context.Context, gameID int64, pickupSite string, before, ratings []PlayerRating, ts string) error {
+	const query = `
+		insert into player_rating_history(
+			game_id, pickup_site, leaderboard_id, rating_before, rating_value, uncertainty_before, uncertainty_after, result, ts
+		)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	if len(before) != len(ratings) {
+		return fmt.Errorf("LogRatingUpdates: got %d ratings before the game and %d after", len(before), len(ratings))
+	}
 
 	var b = &pgx.Batch{}
 
-	for _, r := range ratings {
-		b.Queue(query, gameID, pickupSite, r.ID, r.Rating, r.Result, ts)
+	for i, r := range ratings {
+		b.Queue(query, gameID, pickupSite, r.ID, before[i].Rating, r.Rating, before[i].UncertaintyValue, r.UncertaintyValue, r.Result, ts)
 	}
 
-	br := c.pool.SendBatch(ctx, b)
+	br := c.conn.SendBatch(ctx, b)
 	defer br.Close()
 
 	for i := range ratings {
@@ -222,22 +461,27 @@ func (c *Client) LogRatingUpdates(ctx context.Context, gameID int64, pickupSite
 	return nil
 }
 
-func (c *Client) UpdatePlayerRatings(ctx context.Context, ratings []PlayerRating) error {
+// UpdatePlayerRatings saves ratings after the game played at ts, play and decay timestamps
+// never move back if games are saved out of order
+func (c *Client) UpdatePlayerRatings(ctx context.Context, ratings []PlayerRating, ts string) error {
 	const query = `update player_leaderboard set
                               rating = $1,
                               uncertainty_value = $2,
-                              games_played = $3,
-                              games_tied = $4,
-                              games_won = $5
-                          where id = $6`
+                              volatility = $3,
+                              games_played = $4,
+                              games_tied = $5,
+                              games_won = $6,
+                              last_played_at = greatest(coalesce(last_played_at, $7::timestamp), $7::timestamp),
+                              uncertainty_updated_at = greatest(coalesce(uncertainty_updated_at, $7::timestamp), $7::timestamp)
+                          where id = $8`
 
 	var b = &pgx.Batch{}
 
 	for _, r := range ratings {
-		b.Queue(query, r.Rating, r.UncertaintyValue, r.GamesPlayed, r.GamesTied, r.GamesWon, r.ID)
+		b.Queue(query, r.Rating, r.UncertaintyValue, r.Volatility, r.GamesPlayed, r.GamesTied, r.GamesWon, ts, r.ID)
 	}
 
-	br := c.pool.SendBatch(ctx, b)
+	br := c.conn.SendBatch(ctx, b)
 	defer br.Close()
 
 	for i := range ratings {
@@ -249,37 +493,96 @@ func (c *Client) UpdatePlayerRatings(ctx context.Context, ratings []PlayerRating
 	return nil
 }
 
+// InflateUncertainty grows uncertainty of pickup site leaderboards for the time passed between
+// their last update and ts: variance grows by growthPerDay^2 every day, uncertainty never exceeds maxUncertainty.
+// If steamIDs is empty, leaderboards of all players are updated.
+func (c *Client) InflateUncertainty(ctx context.Context, pickupSite string, steamIDs []int64, ts string, growthPerDay, maxUncertainty float64) error {
+	const query = `
+		update player_leaderboard set
+			uncertainty_value = least($5, sqrt(
+				power(uncertainty_value, 2) + power($4, 2) * extract(epoch from ($3::timestamp - uncertainty_updated_at)) / 86400
+			)),
+			uncertainty_updated_at = $3::timestamp
+		where pickup_site = $1
+			and (coalesce(cardinality($2::bigint[]), 0) = 0 or player_steam_id = any($2::bigint[]))
+			and uncertainty_updated_at < $3::timestamp
+			and uncertainty_value < $5`
+
+	if _, err := c.conn.Exec(ctx, query, pickupSite, steamIDs, ts, growthPerDay, maxUncertainty); err != nil {
+		return fmt.Errorf("InflateUncertainty: %w", err)
+	}
+
+	return nil
+}
+
+// LeaderboardEntry is a rating of player, Ordinal is conservative estimate of rating,
+// rating minus LeaderboardQuery.OrdinalK uncertainties
 type LeaderboardEntry struct {
 	Name        string
 	AvatarURL   string
 	SteamID     int64
 	Rating      float64
+	Uncertainty float64
+	Ordinal     float64
 	GamesWon    int64
 	GamesTied   int64
 	GamesPlayed int64
+	Algorithm   string
+}
+
+// Leaderboard sort keys
+const (
+	SortByRating  = "rating"
+	SortByOrdinal = "ordinal"
+)
+
+type LeaderboardQuery struct {
+	PickupSite string
+	// Class is a player class or OverallClass
+	Class string
+	// ActiveWithinDays excludes players who have not played for more days, if positive
+	ActiveWithinDays int
+	// Provisional selects players with fewer games than minimum games of pickup site instead of qualified ones
+	Provisional bool
+	// SortBy is SortByRating or SortByOrdinal, entries are sorted by rating if empty
+	SortBy   string
+	OrdinalK float64
+	Offset   int
+	Limit    int
 }
 
-func (c *Client) GetLeaderboardForClass(ctx context.Context, playerClass, pickupSite string, offset, limit int) ([]LeaderboardEntry, error) {
-	const minPlayedGames = 15
+// OverallClass is a class of overall leaderboard, which combines all class leaderboards of the player
+const OverallClass = "overall"
+
+// leaderboardFilter selects leaderboard entries of LeaderboardQuery from leaderboard_entries l,
+// players without games are never listed, even with min_games of 0
+const leaderboardFilter = `
+		where l.pickup_site = $1
+			and l.player_class = $2
+			and l.games_played > 0
+			and (l.games_played >= (select min_games from pickup_sites where name = $1)) != $3
+			and ($4 <= 0 or l.last_played_at >= now() - make_interval(days => $4))`
 
+// GetLeaderboardForClass returns players ordered by rating or ordinal rating
+func (c *Client) GetLeaderboardForClass(ctx context.Context, q LeaderboardQuery) ([]LeaderboardEntry, error) {
 	const query = `
 		select
     		p.name,
     		p.avatar_url,
     		p.steam_id,
     		l.rating,
+    		l.uncertainty_value,
+    		l.rating - $7 * l.uncertainty_value as ordinal,
     		l.games_won,
     		l.games_tied,
-    		l.games_played
-		from player_leaderboard l
-		join players p on l.player_steam_id = p.steam_id and l.pickup_site = p.pickup_site
-		where l.pickup_site = $1
-			and l.player_class = $2
-			and l.games_played > $3
-		order by rating desc
-		offset $4 limit $5`
-
-	rows, err := c.pool.Query(ctx, query, pickupSite, playerClass, minPlayedGames, offset, limit)
+    		l.games_played,
+    		l.algorithm
+		from leaderboard_entries l
+		join players p on l.player_steam_id = p.steam_id and l.pickup_site = p.pickup_site` + leaderboardFilter + `
+		order by case when $8 = '` + SortByOrdinal + `' then l.rating - $7 * l.uncertainty_value else l.rating end desc
+		offset $5 limit $6`
+
+	rows, err := c.conn.Query(ctx, query, q.PickupSite, q.Class, q.Provisional, q.ActiveWithinDays, q.Offset, q.Limit, q.OrdinalK, q.SortBy)
 	if err != nil {
 		return nil, fmt.Errorf("GetLeaderboardForClass: failed to query leaderboard entries: %w", err)
 	}
@@ -292,10 +595,272 @@ func (c *Client) GetLeaderboardForClass(ctx context.Context, playerClass, pickup
 	return results, nil
 }
 
+// CountLeaderboardForClass returns total number of leaderboard entries, Offset and Limit of the query are ignored
+func (c *Client) CountLeaderboardForClass(ctx context.Context, q LeaderboardQuery) (int, error) {
+	const query = `select count(*) from leaderboard_entries l` + leaderboardFilter
+
+	var count int
+	if err := c.conn.QueryRow(ctx, query, q.PickupSite, q.Class, q.Provisional, q.ActiveWithinDays).Scan(&count); err != nil {
+		return 0, fmt.Errorf("CountLeaderboardForClass: %w", err)
+	}
+
+	return count, nil
+}
+
+// ClassRating is a rating of player on class or overall leaderboard
+type ClassRating struct {
+	Class       string
+	Rating      float64
+	Uncertainty float64
+	GamesPlayed int64
+	GamesWon    int64
+	GamesTied   int64
+}
+
+// GetPlayerClassRatings returns ratings of player on all class leaderboards with games and overall leaderboard
+func (c *Client) GetPlayerClassRatings(ctx context.Context, pickupSite string, steamID int64) ([]ClassRating, error) {
+	const query = `
+		select player_class, rating, uncertainty_value, games_played, games_won, games_tied
+		from leaderboard_entries
+		where pickup_site = $1 and player_steam_id = $2 and games_played > 0`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, steamID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPlayerClassRatings: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[ClassRating])
+}
+
+// MapStats are results of ended games on the map
+type MapStats struct {
+	Map     string
+	Games   int64
+	RedWins int64
+	BluWins int64
+	Ties    int64
+	// AverageMargin is an average absolute difference of team scores
+	AverageMargin float64
+}
+
+// mapStatsColumns selects MapStats of game_history grouped by game_map
+const mapStatsColumns = `
+		select
+			game_map,
+			count(*),
+			count(*) filter (where red_score > blu_score),
+			count(*) filter (where blu_score > red_score),
+			count(*) filter (where red_score = blu_score),
+			coalesce(avg(abs(red_score - blu_score)), 0)::float8
+		from game_history`
+
+// GetMapStats returns statistics of all maps played on pickup site, most played maps go first
+func (c *Client) GetMapStats(ctx context.Context, pickupSite string) ([]MapStats, error) {
+	const query = mapStatsColumns + `
+		where pickup_site = $1 and state = 'ended'
+		group by game_map
+		order by count(*) desc, game_map`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite)
+	if err != nil {
+		return nil, fmt.Errorf("GetMapStats: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[MapStats])
+}
+
+// GetMapStatsBefore returns statistics of the map for ended games played before the game with gameID
+func (c *Client) GetMapStatsBefore(ctx context.Context, pickupSite, gameMap string, gameID int64) (MapStats, error) {
+	const query = mapStatsColumns + `
+		where pickup_site = $1 and game_map = $2 and game_id < $3 and state = 'ended'
+		group by game_map`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, gameMap, gameID)
+	if err != nil {
+		return MapStats{}, fmt.Errorf("GetMapStatsBefore: %w", err)
+	}
+
+	stats, err := pgx.CollectRows(rows, pgx.RowToStructByPos[MapStats])
+	if err != nil {
+		return MapStats{}, fmt.Errorf("GetMapStatsBefore: %w", err)
+	}
+
+	// map is played for the first time
+	if len(stats) == 0 {
+		return MapStats{Map: gameMap}, nil
+	}
+
+	return stats[0], nil
+}
+
+// PlayerMapStats are results of player's rated games of the class on the map
+type PlayerMapStats struct {
+	Map    string
+	Games  int64
+	Wins   int64
+	Ties   int64
+	Losses int64
+	// RatingChange is a sum of rating changes of the games, games without rating before are not counted
+	RatingChange float64
+}
+
+// GetPlayerMapStats returns player's statistics of the class for every map the player has played, most played maps go first
+func (c *Client) GetPlayerMapStats(ctx context.Context, pickupSite string, steamID int64, class string) ([]PlayerMapStats, error) {
+	const query = `
+		select
+			gh.game_map,
+			count(*),
+			count(*) filter (where rh.result = 'win'),
+			count(*) filter (where rh.result = 'tie'),
+			count(*) filter (where rh.result = 'loss'),
+			coalesce(sum(rh.rating_value - rh.rating_before), 0)::float8
+		from player_rating_history rh
+		join player_leaderboard pl on rh.leaderboard_id = pl.id
+		join game_history gh on rh.game_id = gh.game_id and rh.pickup_site = gh.pickup_site
+		where pl.pickup_site = $1 and pl.player_steam_id = $2 and pl.player_class = $3
+		group by gh.game_map
+		order by count(*) desc, gh.game_map`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, steamID, class)
+	if err != nil {
+		return nil, fmt.Errorf("GetPlayerMapStats: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[PlayerMapStats])
+}
+
+// Pairing is a record of the player's ended games with another player, results are from the player's point of view
+type Pairing struct {
+	SteamID   int64
+	Name      string
+	AvatarURL string
+	Games     int64
+	Wins      int64
+	Ties      int64
+	Losses    int64
+}
+
+// GetTeammates returns players who played in the same team with the player playing the class,
+// players with most wins together go first
+func (c *Client) GetTeammates(ctx context.Context, pickupSite string, steamID int64, class string, limit int) ([]Pairing, error) {
+	pairings, err := c.getPairings(ctx, pickupSite, steamID, class, true, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetTeammates: %w", err)
+	}
+
+	return pairings, nil
+}
+
+// GetOpponents returns players who played against the player playing the class,
+// players the player lost to most go first
+func (c *Client) GetOpponents(ctx context.Context, pickupSite string, steamID int64, class string, limit int) ([]Pairing, error) {
+	pairings, err := c.getPairings(ctx, pickupSite, steamID, class, false, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetOpponents: %w", err)
+	}
+
+	return pairings, nil
+}
+
+func (c *Client) getPairings(ctx context.Context, pickupSite string, steamID int64, class string, sameTeam bool, limit int) ([]Pairing, error) {
+	const query = `
+		with player_games as (
+			select
+				gp.game_id,
+				gp.team,
+				case
+					when gh.red_score = gh.blu_score then 'tie'
+					when (gh.red_score > gh.blu_score) = (gp.team = 'red') then 'win'
+					else 'loss'
+				end as result
+			from game_players gp
+			join game_history gh on gh.game_id = gp.game_id and gh.pickup_site = gp.pickup_site
+			where gp.pickup_site = $1 and gp.steam_id = $2 and gp.game_class = $3 and gh.state = 'ended'
+		)
+		select
+			other.steam_id,
+			coalesce(p.name, ''),
+			coalesce(p.avatar_url, ''),
+			count(*) as games,
+			count(*) filter (where pg.result = 'win') as wins,
+			count(*) filter (where pg.result = 'tie') as ties,
+			count(*) filter (where pg.result = 'loss') as losses
+		from player_games pg
+		join game_players other on other.game_id = pg.game_id
+			and other.pickup_site = $1
+			and other.steam_id != $2
+			and (other.team = pg.team) = $4
+		left join players p on p.steam_id = other.steam_id and p.pickup_site = other.pickup_site
+		group by other.steam_id, p.name, p.avatar_url
+		order by case when $4 then count(*) filter (where pg.result = 'win') else count(*) filter (where pg.result = 'loss') end desc,
+			games desc
+		limit $5`
+
+	rows, err := c.conn.Query(ctx, query, pickupSite, steamID, class, sameTeam, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Pairing])
+}
+
+// Record is a number of games with their results
+type Record struct {
+	Games  int64
+	Wins   int64
+	Ties   int64
+	Losses int64
+}
+
+// HeadToHead is a record of ended games of two players from the first player's point of view
+type HeadToHead struct {
+	Together Record
+	Against  Record
+}
+
+// GetHeadToHead returns records of games where players played in the same team and against each other
+func (c *Client) GetHeadToHead(ctx context.Context, pickupSite string, steamID, otherSteamID int64) (HeadToHead, error) {
+	const query = `
+		with games as (
+			select
+				a.team = b.team as together,
+				case
+					when gh.red_score = gh.blu_score then 'tie'
+					when (gh.red_score > gh.blu_score) = (a.team = 'red') then 'win'
+					else 'loss'
+				end as result
+			from game_players a
+			join game_players b on b.game_id = a.game_id and b.pickup_site = a.pickup_site and b.steam_id = $3
+			join game_history gh on gh.game_id = a.game_id and gh.pickup_site = a.pickup_site
+			where a.pickup_site = $1 and a.steam_id = $2 and gh.state = 'ended'
+		)
+		select
+			count(*) filter (where together),
+			count(*) filter (where together and result = 'win'),
+			count(*) filter (where together and result = 'tie'),
+			count(*) filter (where together and result = 'loss'),
+			count(*) filter (where not together),
+			count(*) filter (where not together and result = 'win'),
+			count(*) filter (where not together and result = 'tie'),
+			count(*) filter (where not together and result = 'loss')
+		from games`
+
+	var h HeadToHead
+	err := c.conn.QueryRow(ctx, query, pickupSite, steamID, otherSteamID).Scan(
+		&h.Together.Games, &h.Together.Wins, &h.Together.Ties, &h.Together.Losses,
+		&h.Against.Games, &h.Against.Wins, &h.Against.Ties, &h.Against.Losses,
+	)
+	if err != nil {
+		return HeadToHead{}, fmt.Errorf("GetHeadToHead: %w", err)
+	}
+
+	return h, nil
+}
+
 func (c *Client) GetAvailablePickupSites(ctx context.Context) ([]string, error) {
-	const query = `select distinct pickup_site from game_history`
+	const query = `select name from pickup_sites order by name`
 
-	rows, err := c.pool.Query(ctx, query)
+	rows, err := c.conn.Query(ctx, query)
 	if err != nil {
 		return nil, nil
 	}
@@ -303,26 +868,83 @@ func (c *Client) GetAvailablePickupSites(ctx context.Context) ([]string, error)
 	return pgx.CollectRows(rows, pgx.RowTo[string])
 }
 
-func (c *Client) GetPlayerRatingHistoryForClass(ctx context.Context, steamID int64, class string) ([]RatingUpdate, error) {
+func (c *Client) GetPickupSiteClasses(ctx context.Context, pickupSite string) ([]string, error) {
+	const query = `select classes from pickup_sites where name = $1`
+
+	var classes []string
+	if err := c.conn.QueryRow(ctx, query, pickupSite).Scan(&classes); err != nil {
+		return nil, fmt.Errorf("GetPickupSiteClasses: %w", err)
+	}
+
+	return classes, nil
+}
+
+// GetPickupSiteMinGames returns number of games players need to appear on leaderboards of pickup site
+func (c *Client) GetPickupSiteMinGames(ctx context.Context, pickupSite string) (int, error) {
+	const query = `select min_games from pickup_sites where name = $1`
+
+	var minGames int
+	if err := c.conn.QueryRow(ctx, query, pickupSite).Scan(&minGames); err != nil {
+		return 0, fmt.Errorf("GetPickupSiteMinGames: %w", err)
+	}
+
+	return minGames, nil
+}
+
+// SetPickupSiteMinGames registers pickup site if needed and sets number of games players need to appear on its leaderboards
+func (c *Client) SetPickupSiteMinGames(ctx context.Context, pickupSite string, minGames int) error {
+	const query = `
+		insert into pickup_sites(name, min_games) values ($1, $2)
+		on conflict (name) do update set min_games = excluded.min_games`
+
+	if _, err := c.conn.Exec(ctx, query, pickupSite, minGames); err != nil {
+		return fmt.Errorf("SetPickupSiteMinGames: %w", err)
+	}
+
+	return nil
+}
+
+// AddPickupSiteClasses registers pickup site if needed and appends classes it does not have yet
+func (c *Client) AddPickupSiteClasses(ctx context.Context, pickupSite string, classes []string) error {
+	const query = `
+		insert into pickup_sites(name, classes) values ($1, $2)
+		on conflict (name) do update set classes = pickup_sites.classes || array(
+			select c from unnest(excluded.classes) with ordinality as new_classes(c, i)
+			where not c = any(pickup_sites.classes)
+			order by i
+		)`
+
+	if _, err := c.conn.Exec(ctx, query, pickupSite, classes); err != nil {
+		return fmt.Errorf("AddPickupSiteClasses: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) GetPlayerRatingHistoryForClass(ctx context.Context, pickupSite string, steamID int64, class string) ([]RatingUpdate, error) {
 	const query = `
 		select
 			gh.game_id,
 			gh.pickup_id,
 			gh.game_map,
-			rating_value,
+			rh.rating_before,
+			rh.rating_value,
+			rh.uncertainty_before,
+			rh.uncertainty_after,
 			result,
 			gh.red_score,
 			gh.blu_score,
 			to_char(rh.ts, 'YYYY/MM/DD'),
-			to_char(rh.ts, 'HH24:MM:SS')
+			to_char(rh.ts, 'HH24:MI:SS'),
+			rh.ts::text
 		from player_rating_history rh
 		join player_leaderboard pl on rh.leaderboard_id = pl.id
-		join game_history gh on rh.game_id = gh.game_id
+		join game_history gh on rh.game_id = gh.game_id and rh.pickup_site = gh.pickup_site
 		where
-			player_steam_id = $1 and player_class = $2
+			pl.pickup_site = $1 and player_steam_id = $2 and player_class = $3
 		order by rh.ts`
 
-	rows, err := c.pool.Query(ctx, query, steamID, class)
+	rows, err := c.conn.Query(ctx, query, pickupSite, steamID, class)
 	if err != nil {
 		return nil, fmt.Errorf("GetPlayerRatingHistoryForClass: quering rows: %w", err)
 	}
@@ -330,11 +952,31 @@ func (c *Client) GetPlayerRatingHistoryForClass(ctx context.Context, steamID int
 	return pgx.CollectRows(rows, pgx.RowToStructByPos[RatingUpdate])
 }
 
+// SearchPlayers returns players of pickup site with given steamID or name containing query, case-insensitive.
+// Exact steamID match goes first, then names most similar to query.
+func (c *Client) SearchPlayers(ctx context.Context, pickupSite, query string, steamID int64, limit int) ([]Player, error) {
+	const sql = `
+		select coalesce(name, ''), coalesce(avatar_url, ''), steam_id, pickup_site
+		from players
+		where pickup_site = $1 and (steam_id = $2 or name ilike '%' || $3 || '%')
+		order by steam_id = $2 desc, similarity(name, $4) desc, name
+		limit $5`
+
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+
+	rows, err := c.conn.Query(ctx, sql, pickupSite, steamID, pattern, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("SearchPlayers: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Player])
+}
+
 func (c *Client) GetPlayerName(ctx context.Context, pickupSite string, steamID int64) (string, error) {
 	const query = `select name from players where pickup_site = $1 and steam_id = $2`
 
 	var playerName string
-	if err := c.pool.QueryRow(ctx, query, pickupSite, steamID).Scan(&playerName); err != nil {
+	if err := c.conn.QueryRow(ctx, query, pickupSite, steamID).Scan(&playerName); err != nil {
 		return "", fmt.Errorf("GetPlayerName: quering rows: %w", err)
 	}
 
@@ -0,0 +1,244 @@
+package http
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/condensedtea/pickup-ratings/api"
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/samber/lo"
+)
+
+const (
+	defaultAPILimit = 50
+	maxAPILimit     = 100
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (s *Server) registerAPI() {
+	s.app.Get("/api/openapi.json", func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return ctx.Send(openAPISpec)
+	})
+
+	v1 := s.app.Group("/api/v1")
+
+	v1.Get("/sites", s.apiPickupSites)
+	v1.Get("/sites/:pickupSite/leaderboards/:class", s.apiLeaderboard)
+	v1.Get("/sites/:pickupSite/players/:steamID/history/:class", s.apiPlayerHistory)
+	v1.Get("/sites/:pickupSite/games/:gameID", s.apiGame)
+	v1.Post("/sites/:pickupSite/predict", s.apiPredict)
+	v1.Post("/sites/:pickupSite/balance", s.apiBalance)
+}
+
+func (s *Server) apiPickupSites(ctx *fiber.Ctx) error {
+	sites, err := s.db.GetAvailablePickupSites(ctx.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get availible pickup sites: %w", err)
+	}
+
+	result := make([]api.PickupSite, len(sites))
+	for i, site := range sites {
+		classes, err := s.db.GetPickupSiteClasses(ctx.Context(), site)
+		if err != nil {
+			return fmt.Errorf("failed to get pickup site classes: %w", err)
+		}
+
+		result[i] = api.PickupSite{Name: site, Classes: classes}
+	}
+
+	return ctx.JSON(result)
+}
+
+func (s *Server) apiLeaderboard(ctx *fiber.Ctx) error {
+	pickupSite, class := ctx.Params("pickupSite"), ctx.Params("class")
+
+	offset := ctx.QueryInt("offset", 0)
+	limit := ctx.QueryInt("limit", defaultAPILimit)
+	if offset < 0 || limit <= 0 || limit > maxAPILimit {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("offset must be positive and limit must be in range 1..%d", maxAPILimit))
+	}
+
+	sortBy, ok := sortParam(ctx)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("sort must be %s or %s", db.SortByRating, db.SortByOrdinal))
+	}
+
+	q := db.LeaderboardQuery{
+		PickupSite:       pickupSite,
+		Class:            class,
+		ActiveWithinDays: s.cfg.InactiveDays,
+		Provisional:      ctx.QueryBool("provisional"),
+		SortBy:           sortBy,
+		OrdinalK:         s.cfg.OrdinalK,
+		Offset:           offset,
+		Limit:            limit,
+	}
+
+	entries, err := s.db.GetLeaderboardForClass(ctx.Context(), q)
+	if err != nil {
+		return err
+	}
+
+	total, err := s.db.CountLeaderboardForClass(ctx.Context(), q)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(api.Leaderboard{
+		PickupSite:  pickupSite,
+		Class:       class,
+		Offset:      offset,
+		Limit:       limit,
+		Total:       total,
+		SortBy:      sortBy,
+		Provisional: q.Provisional,
+		Entries: lo.Map(entries, func(e db.LeaderboardEntry, i int) api.LeaderboardEntry {
+			return api.LeaderboardEntry{
+				Position:      offset + i + 1,
+				SteamID:       e.SteamID,
+				Name:          e.Name,
+				AvatarURL:     e.AvatarURL,
+				Rating:        apiRating(e.Rating),
+				Uncertainty:   apiRating(e.Uncertainty),
+				OrdinalRating: apiRating(e.Ordinal),
+				GamesPlayed:   e.GamesPlayed,
+				GamesWon:      e.GamesWon,
+				GamesTied:     e.GamesTied,
+				Algorithm:     e.Algorithm,
+			}
+		}),
+	})
+}
+
+func (s *Server) apiPlayerHistory(ctx *fiber.Ctx) error {
+	pickupSite, class := ctx.Params("pickupSite"), ctx.Params("class")
+
+	steamID, err := steamIDParam(ctx, "steamID")
+	if err != nil {
+		return err
+	}
+
+	playerName, err := s.db.GetPlayerName(ctx.Context(), pickupSite, steamID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, "player not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get player's name: %w", err)
+	}
+
+	history, err := s.db.GetPlayerRatingHistoryForClass(ctx.Context(), pickupSite, steamID, class)
+	if err != nil {
+		return fmt.Errorf("failed to get player's history: %w", err)
+	}
+
+	return ctx.JSON(api.PlayerHistory{
+		PickupSite: pickupSite,
+		SteamID:    steamID,
+		Name:       playerName,
+		Class:      class,
+		Updates: lo.Map(history, func(u db.RatingUpdate, _ int) api.RatingUpdate {
+			return api.RatingUpdate{
+				GameID:   u.GameID,
+				PickupID: u.PickupID,
+				Map:      u.GameMap,
+				Rating:   apiRating(u.Rating),
+				Result:   u.Result,
+				RedScore: u.RedScore,
+				BluScore: u.BluScore,
+				PlayedAt: apiTimestamp(u.Ts),
+
+				RatingBefore:      optionalAPIRating(u.RatingBefore),
+				Uncertainty:       optionalAPIRating(u.Uncertainty),
+				UncertaintyBefore: optionalAPIRating(u.UncertaintyBefore),
+			}
+		}),
+	})
+}
+
+func (s *Server) apiGame(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+
+	gameID, err := ctx.ParamsInt("gameID")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to parse gameID: %s", err))
+	}
+
+	game, err := s.db.GetGame(ctx.Context(), pickupSite, int64(gameID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, "game not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get game: %w", err)
+	}
+
+	lineup, err := s.db.GetGameLineup(ctx.Context(), pickupSite, int64(gameID))
+	if err != nil {
+		return fmt.Errorf("failed to get game lineup: %w", err)
+	}
+
+	return ctx.JSON(api.Game{
+		ID:       game.ID,
+		PickupID: game.PickupID,
+		Map:      game.Map,
+		State:    game.State,
+		RedScore: game.RedScore,
+		BluScore: game.BluScore,
+		PlayedAt: apiTimestamp(game.Ts),
+		Players: lo.Map(lineup, func(p db.LineupPlayer, _ int) api.GamePlayer {
+			return api.GamePlayer{
+				SteamID: p.SteamID,
+				Name:    p.Name,
+				Team:    p.Team,
+				Class:   p.Class,
+			}
+		}),
+		RedWinProbability: game.RedWinProbability,
+	})
+}
+
+// errorHandler responds with api.Error to failed API requests and uses default fiber handler for pages.
+// Unexpected errors of API requests are logged and not exposed to clients.
+func errorHandler(ctx *fiber.Ctx, err error) error {
+	if !strings.HasPrefix(ctx.Path(), "/api/") {
+		return fiber.DefaultErrorHandler(ctx, err)
+	}
+
+	e := fiber.ErrInternalServerError
+	if !errors.As(err, &e) {
+		slog.Error("failed to handle API request", "method", ctx.Method(), "path", ctx.Path(), "error", err)
+	}
+
+	return ctx.Status(e.Code).JSON(api.Error{Error: e.Message})
+}
+
+// apiRating converts rating to the value displayed on the website
+func apiRating(v float64) float64 {
+	return v * 100
+}
+
+// optionalAPIRating converts rating values that may be unknown
+func optionalAPIRating(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+
+	r := apiRating(*v)
+	return &r
+}
+
+// apiTimestamp converts postgres timestamp text to RFC 3339
+func apiTimestamp(ts string) string {
+	t, err := parseTimestamp(ts)
+	if err != nil {
+		return ts
+	}
+
+	return t.Format(time.RFC3339)
+}
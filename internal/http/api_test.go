@@ -0,0 +1,298 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/api"
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	testPickupSite = "tf2pickup.test"
+	testSteamID    = 76561198011558250
+)
+
+// fakeDatabase serves test data of a single pickup site, err is returned by every method when set
+type fakeDatabase struct {
+	classes []string
+	players map[int64]string
+	entries []db.LeaderboardEntry
+	history map[int64][]db.RatingUpdate
+	games   map[int64]db.Game
+	lineups map[int64][]db.LineupPlayer
+	ratings []db.PlayerRating
+
+	err error
+}
+
+func newFakeDatabase() *fakeDatabase {
+	entries := make([]db.LeaderboardEntry, 5)
+	for i := range entries {
+		entries[i] = db.LeaderboardEntry{
+			Name:        "player",
+			SteamID:     testSteamID + int64(i),
+			Rating:      float64(30 - i),
+			Uncertainty: 1,
+			GamesPlayed: 20,
+			Algorithm:   "openskill",
+		}
+	}
+
+	return &fakeDatabase{
+		classes: []string{"scout", "soldier"},
+		players: map[int64]string{testSteamID: "player", testSteamID + 1: "teammate"},
+		entries: entries,
+		history: map[int64][]db.RatingUpdate{
+			testSteamID: {{GameID: 1, PickupID: "game-1", GameMap: "cp_process_final", Rating: 25.5, Result: "win", RedScore: 5, Ts: "2023-10-01 18:30:00"}},
+		},
+		games: map[int64]db.Game{
+			1: {ID: 1, PickupID: "game-1", Map: "cp_process_final", PickupSite: testPickupSite, RedScore: 5, State: "ended", Ts: "2023-10-01 18:30:00"},
+		},
+		lineups: map[int64][]db.LineupPlayer{
+			1: {{SteamID: testSteamID, Name: "player", Team: "red", Class: "scout"}, {SteamID: testSteamID + 1, Name: "teammate", Team: "blu", Class: "scout"}},
+		},
+		ratings: []db.PlayerRating{
+			{SteamID: testSteamID, Class: "scout", Rating: 30, UncertaintyValue: 5, Algorithm: "openskill"},
+			{SteamID: testSteamID + 1, Class: "scout", Rating: 20, UncertaintyValue: 5, Algorithm: "openskill"},
+		},
+	}
+}
+
+func (f *fakeDatabase) GetAvailablePickupSites(ctx context.Context) ([]string, error) {
+	return []string{testPickupSite}, f.err
+}
+
+func (f *fakeDatabase) GetLeaderboardForClass(ctx context.Context, q db.LeaderboardQuery) ([]db.LeaderboardEntry, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	entries := f.entries[min(q.Offset, len(f.entries)):]
+	return entries[:min(q.Limit, len(entries))], nil
+}
+
+func (f *fakeDatabase) CountLeaderboardForClass(ctx context.Context, q db.LeaderboardQuery) (int, error) {
+	return len(f.entries), f.err
+}
+
+func (f *fakeDatabase) GetPlayerRatingHistoryForClass(ctx context.Context, pickupSite string, steamID int64, class string) ([]db.RatingUpdate, error) {
+	return f.history[steamID], f.err
+}
+
+func (f *fakeDatabase) GetPlayerName(ctx context.Context, pickupSite string, steamID int64) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+
+	name, ok := f.players[steamID]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+
+	return name, nil
+}
+
+func (f *fakeDatabase) GetPickupSiteClasses(ctx context.Context, pickupSite string) ([]string, error) {
+	if pickupSite != testPickupSite {
+		return nil, pgx.ErrNoRows
+	}
+
+	return f.classes, f.err
+}
+
+func (f *fakeDatabase) GetPickupSiteMinGames(ctx context.Context, pickupSite string) (int, error) {
+	return 16, f.err
+}
+
+func (f *fakeDatabase) GetPlayerClassRatings(ctx context.Context, pickupSite string, steamID int64) ([]db.ClassRating, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetMapStats(ctx context.Context, pickupSite string) ([]db.MapStats, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetPlayerMapStats(ctx context.Context, pickupSite string, steamID int64, class string) ([]db.PlayerMapStats, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetTeammates(ctx context.Context, pickupSite string, steamID int64, class string, limit int) ([]db.Pairing, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetOpponents(ctx context.Context, pickupSite string, steamID int64, class string, limit int) ([]db.Pairing, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetHeadToHead(ctx context.Context, pickupSite string, steamID, otherSteamID int64) (db.HeadToHead, error) {
+	return db.HeadToHead{}, f.err
+}
+
+func (f *fakeDatabase) GetGame(ctx context.Context, pickupSite string, gameID int64) (db.Game, error) {
+	if f.err != nil {
+		return db.Game{}, f.err
+	}
+
+	game, ok := f.games[gameID]
+	if !ok {
+		return db.Game{}, pgx.ErrNoRows
+	}
+
+	return game, nil
+}
+
+func (f *fakeDatabase) GetGameLineup(ctx context.Context, pickupSite string, gameID int64) ([]db.LineupPlayer, error) {
+	return f.lineups[gameID], f.err
+}
+
+func (f *fakeDatabase) SearchPlayers(ctx context.Context, pickupSite, query string, steamID int64, limit int) ([]db.Player, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetGameRatings(ctx context.Context, pickupSite string, gameID int64) ([]db.GameRating, error) {
+	return nil, f.err
+}
+
+func (f *fakeDatabase) GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int64, pickupSite string) ([]db.PlayerRating, error) {
+	return f.ratings, f.err
+}
+
+// testRequest sends request to the server and returns response status and body
+func testRequest(t *testing.T, s *Server, method, target, body string) (int, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", method, target, err)
+	}
+
+	return resp.StatusCode, b
+}
+
+func TestAPILeaderboardPagination(t *testing.T) {
+	s := NewServer(newFakeDatabase(), Config{OrdinalK: DefaultOrdinalK})
+
+	status, body := testRequest(t, s, "GET", "/api/v1/sites/tf2pickup.test/leaderboards/scout?offset=2&limit=2", "")
+	if status != 200 {
+		t.Fatalf("status = %d, want 200: %s", status, body)
+	}
+
+	var leaderboard api.Leaderboard
+	if err := json.Unmarshal(body, &leaderboard); err != nil {
+		t.Fatal(err)
+	}
+
+	if leaderboard.Offset != 2 || leaderboard.Limit != 2 || leaderboard.Total != 5 || leaderboard.SortBy != db.SortByRating {
+		t.Errorf("leaderboard offset, limit, total, sort = %d, %d, %d, %q, want 2, 2, 5, %q",
+			leaderboard.Offset, leaderboard.Limit, leaderboard.Total, leaderboard.SortBy, db.SortByRating)
+	}
+
+	if len(leaderboard.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(leaderboard.Entries))
+	}
+	for i, e := range leaderboard.Entries {
+		if e.Position != 3+i || e.SteamID != testSteamID+2+int64(i) || e.Rating != float64(28-i)*100 {
+			t.Errorf("entry %d = %+v, want position %d of player %d with rating %d", i, e, 3+i, testSteamID+2+i, (28-i)*100)
+		}
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"negative offset", "GET", "/api/v1/sites/tf2pickup.test/leaderboards/scout?offset=-1", "", 400, "offset must be positive and limit must be in range 1..100"},
+		{"zero limit", "GET", "/api/v1/sites/tf2pickup.test/leaderboards/scout?limit=0", "", 400, "offset must be positive and limit must be in range 1..100"},
+		{"limit too large", "GET", "/api/v1/sites/tf2pickup.test/leaderboards/scout?limit=101", "", 400, "offset must be positive and limit must be in range 1..100"},
+		{"unknown sort", "GET", "/api/v1/sites/tf2pickup.test/leaderboards/scout?sort=name", "", 400, "sort must be rating or ordinal"},
+		{"unknown player", "GET", "/api/v1/sites/tf2pickup.test/players/76561198011558259/history/scout", "", 404, "player not found"},
+		{"invalid steam id", "GET", "/api/v1/sites/tf2pickup.test/players/abc/history/scout", "", 400, ""},
+		{"unknown game", "GET", "/api/v1/sites/tf2pickup.test/games/2", "", 404, "game not found"},
+		{"invalid game id", "GET", "/api/v1/sites/tf2pickup.test/games/abc", "", 400, ""},
+		{"empty team", "POST", "/api/v1/sites/tf2pickup.test/predict", `{"red": [], "blu": []}`, 400, "both teams must have players"},
+		{"too many players to predict", "POST", "/api/v1/sites/tf2pickup.test/predict", lineupBody(t, map[string]int{"red": 10, "blu": 9}), 400, "teams must have at most 18 players in total"},
+		{"too many players to balance", "POST", "/api/v1/sites/tf2pickup.test/balance", lineupBody(t, map[string]int{"players": 20}), 400, "number of players must be in range 1..18"},
+		{"unknown pickup site", "POST", "/api/v1/sites/unknown/balance", `{"players": [{"steamId": "76561198011558250", "class": "scout"}]}`, 404, "pickup site not found"},
+	}
+
+	s := NewServer(newFakeDatabase(), Config{OrdinalK: DefaultOrdinalK})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := testRequest(t, s, tt.method, tt.target, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", status, tt.wantStatus, body)
+			}
+
+			var apiErr api.Error
+			if err := json.Unmarshal(body, &apiErr); err != nil {
+				t.Fatalf("error body %q is not api.Error: %v", body, err)
+			}
+
+			if apiErr.Error == "" || tt.wantError != "" && apiErr.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", apiErr.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+// lineupBody returns request body with given numbers of scouts in lineup fields
+func lineupBody(t *testing.T, sizes map[string]int) string {
+	t.Helper()
+
+	lineups := map[string][]api.LineupSlot{}
+	for field, n := range sizes {
+		for i := 0; i < n; i++ {
+			lineups[field] = append(lineups[field], api.LineupSlot{SteamID: testSteamID + int64(i), Class: "scout"})
+		}
+	}
+
+	body, err := json.Marshal(lineups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(body)
+}
+
+func TestAPIInternalErrorIsNotExposed(t *testing.T) {
+	database := newFakeDatabase()
+	database.err = errors.New(`GetLeaderboardForClass: ERROR: relation "leaderboard_entries" does not exist`)
+
+	s := NewServer(database, Config{OrdinalK: DefaultOrdinalK})
+
+	status, body := testRequest(t, s, "GET", "/api/v1/sites/tf2pickup.test/leaderboards/scout", "")
+	if status != 500 {
+		t.Errorf("status = %d, want 500", status)
+	}
+
+	var apiErr api.Error
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		t.Fatalf("error body %q is not api.Error: %v", body, err)
+	}
+
+	if apiErr.Error != "Internal Server Error" {
+		t.Errorf("error = %q, want %q", apiErr.Error, "Internal Server Error")
+	}
+}
@@ -0,0 +1,222 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"time"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 300
+
+	// margins of plot area, left one fits rating labels and top one fits the title
+	chartMarginLeft   = 50
+	chartMarginRight  = 20
+	chartMarginTop    = 40
+	chartMarginBottom = 30
+)
+
+// chartPalette is used for series of the chart in order
+var chartPalette = []string{"#4dffdb", "#ffb84d", "#b84dff", "#ff4d88"}
+
+var resultColors = map[string]string{
+	"win":  "limegreen",
+	"tie":  "yellow",
+	"loss": "red",
+}
+
+// chartSeries is rating history of a single player, drawn as a line with uncertainty band
+type chartSeries struct {
+	Label   string
+	Updates []db.RatingUpdate
+}
+
+type chartPoint struct {
+	ts     time.Time
+	rating float64
+	// low and high are rating minus and plus uncertainty, equal to rating when uncertainty is unknown
+	low, high float64
+	band      bool
+	result    string
+}
+
+func (s *Server) playerChart(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+
+	steamID, err := steamIDParam(ctx, "steamID")
+	if err != nil {
+		return err
+	}
+
+	_, gameClass, err := s.pickupSiteClasses(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	playerName, err := s.db.GetPlayerName(ctx.Context(), pickupSite, steamID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get player's name: %w", err)
+	}
+
+	history, err := s.db.GetPlayerRatingHistoryForClass(ctx.Context(), pickupSite, steamID, gameClass)
+	if err != nil {
+		return fmt.Errorf("failed to get player's history: %w", err)
+	}
+
+	title := fmt.Sprintf("%s · %s · %s", playerName, gameClass, pickupSite)
+
+	ctx.Set(fiber.HeaderContentType, "image/svg+xml")
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=600")
+
+	return ctx.Send(renderRatingChart(title, []chartSeries{{Label: playerName, Updates: history}}))
+}
+
+// renderRatingChart draws rating histories as SVG line chart over time. Uncertainty bands are drawn
+// for updates with known uncertainty and every game is marked with the color of its result.
+func renderRatingChart(title string, series []chartSeries) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	b.WriteString(`<rect width="100%" height="100%" fill="#282536"/>`)
+	fmt.Fprintf(&b, `<text x="%d" y="24" fill="white" font-size="16">%s</text>`, chartMarginLeft, html.EscapeString(title))
+
+	points := make([][]chartPoint, len(series))
+	var all []chartPoint
+	for i, s := range series {
+		points[i] = chartPoints(s.Updates)
+		all = append(all, points[i]...)
+	}
+
+	if len(all) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="white" text-anchor="middle">No games played</text>`, chartWidth/2, chartHeight/2)
+		b.WriteString(`</svg>`)
+		return b.Bytes()
+	}
+
+	minTs, maxTs := all[0].ts, all[0].ts
+	minRating, maxRating := all[0].low, all[0].high
+	for _, p := range all {
+		if p.ts.Before(minTs) {
+			minTs = p.ts
+		}
+		if p.ts.After(maxTs) {
+			maxTs = p.ts
+		}
+		minRating, maxRating = math.Min(minRating, p.low), math.Max(maxRating, p.high)
+	}
+
+	step := ratingTickStep(maxRating - minRating)
+	minRating, maxRating = math.Floor(minRating/step)*step, math.Ceil(maxRating/step)*step
+	if minRating == maxRating {
+		minRating, maxRating = minRating-step, maxRating+step
+	}
+
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBottom)
+
+	x := func(ts time.Time) float64 {
+		if !maxTs.After(minTs) {
+			return chartMarginLeft + plotWidth/2
+		}
+		return chartMarginLeft + plotWidth*float64(ts.Sub(minTs))/float64(maxTs.Sub(minTs))
+	}
+	y := func(rating float64) float64 {
+		return chartMarginTop + plotHeight*(maxRating-rating)/(maxRating-minRating)
+	}
+
+	for r := minRating; r <= maxRating+step/2; r += step {
+		fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" stroke="#4d4d93" stroke-width="1"/>`,
+			chartMarginLeft, chartWidth-chartMarginRight, y(r), y(r))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" fill="white" text-anchor="end" dominant-baseline="middle">%s</text>`,
+			chartMarginLeft-6, y(r), ratingLabel(r))
+	}
+
+	fmt.Fprintf(&b, `<text x="%d" y="%d" fill="white">%s</text>`, chartMarginLeft, chartHeight-10, minTs.Format("2006/01/02"))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" fill="white" text-anchor="end">%s</text>`, chartWidth-chartMarginRight, chartHeight-10, maxTs.Format("2006/01/02"))
+
+	for i, s := range series {
+		color := chartPalette[i%len(chartPalette)]
+		writeSeries(&b, points[i], color, x, y)
+
+		if len(series) > 1 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s" text-anchor="end">%s</text>`,
+				chartWidth-chartMarginRight, 24+i*14, color, html.EscapeString(s.Label))
+		}
+	}
+
+	b.WriteString(`</svg>`)
+
+	return b.Bytes()
+}
+
+func writeSeries(b *bytes.Buffer, points []chartPoint, color string, x func(time.Time) float64, y func(float64) float64) {
+	var band []chartPoint
+	for _, p := range points {
+		if p.band {
+			band = append(band, p)
+		}
+	}
+
+	if len(band) > 1 {
+		b.WriteString(`<polygon fill-opacity="0.2" fill="` + color + `" points="`)
+		for _, p := range band {
+			fmt.Fprintf(b, "%.1f,%.1f ", x(p.ts), y(p.high))
+		}
+		for i := len(band) - 1; i >= 0; i-- {
+			fmt.Fprintf(b, "%.1f,%.1f ", x(band[i].ts), y(band[i].low))
+		}
+		b.WriteString(`"/>`)
+	}
+
+	b.WriteString(`<polyline fill="none" stroke-width="2" stroke="` + color + `" points="`)
+	for _, p := range points {
+		fmt.Fprintf(b, "%.1f,%.1f ", x(p.ts), y(p.rating))
+	}
+	b.WriteString(`"/>`)
+
+	for _, p := range points {
+		fmt.Fprintf(b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"/>`, x(p.ts), y(p.rating), resultColors[p.result])
+	}
+}
+
+// chartPoints converts rating updates to chart points, updates with malformed timestamps are skipped
+func chartPoints(updates []db.RatingUpdate) []chartPoint {
+	points := make([]chartPoint, 0, len(updates))
+	for _, u := range updates {
+		ts, err := parseTimestamp(u.Ts)
+		if err != nil {
+			continue
+		}
+
+		p := chartPoint{ts: ts, rating: u.Rating, low: u.Rating, high: u.Rating, result: u.Result}
+		if u.Uncertainty != nil {
+			p.low, p.high, p.band = u.Rating-*u.Uncertainty, u.Rating+*u.Uncertainty, true
+		}
+
+		points = append(points, p)
+	}
+
+	return points
+}
+
+// ratingTickStep returns distance between rating grid lines, so that there are at most 6 of them
+func ratingTickStep(ratingRange float64) float64 {
+	for _, step := range []float64{0.5, 1, 2, 2.5, 5, 10, 20, 25, 50} {
+		if ratingRange/step <= 5 {
+			return step
+		}
+	}
+
+	return 100
+}
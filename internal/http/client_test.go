@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/condensedtea/pickup-ratings/api"
+	"github.com/gofiber/fiber/v2"
+)
+
+// startTestServer serves s on a local port until the test ends and returns its base URL
+func startTestServer(t *testing.T, s *Server) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.app.Listener(ln)
+	t.Cleanup(func() {
+		if err := s.app.Shutdown(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return "http://" + ln.Addr().String()
+}
+
+func TestClient(t *testing.T) {
+	baseURL := startTestServer(t, NewServer(newFakeDatabase(), Config{OrdinalK: DefaultOrdinalK}))
+	client := api.NewClient(baseURL, http.DefaultClient)
+	ctx := context.Background()
+
+	t.Run("PickupSites", func(t *testing.T) {
+		sites, err := client.PickupSites(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sites) != 1 || sites[0].Name != testPickupSite || strings.Join(sites[0].Classes, ",") != "scout,soldier" {
+			t.Errorf("PickupSites() = %+v", sites)
+		}
+	})
+
+	t.Run("Leaderboard", func(t *testing.T) {
+		leaderboard, err := client.Leaderboard(ctx, testPickupSite, "scout", 1, 2, "ordinal", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if leaderboard.SortBy != "ordinal" || !leaderboard.Provisional || leaderboard.Total != 5 || len(leaderboard.Entries) != 2 {
+			t.Errorf("Leaderboard() = %+v", leaderboard)
+		}
+		if e := leaderboard.Entries[0]; e.Position != 2 || e.SteamID != testSteamID+1 || e.Uncertainty != 100 {
+			t.Errorf("first entry = %+v", e)
+		}
+	})
+
+	t.Run("PlayerHistory", func(t *testing.T) {
+		history, err := client.PlayerHistory(ctx, testPickupSite, testSteamID, "scout")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if history.SteamID != testSteamID || history.Name != "player" || len(history.Updates) != 1 {
+			t.Fatalf("PlayerHistory() = %+v", history)
+		}
+		if u := history.Updates[0]; u.Rating != 2550 || u.PlayedAt != "2023-10-01T18:30:00Z" || u.RatingBefore != nil {
+			t.Errorf("update = %+v", u)
+		}
+	})
+
+	t.Run("Game", func(t *testing.T) {
+		game, err := client.Game(ctx, testPickupSite, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if game.ID != 1 || game.State != "ended" || len(game.Players) != 2 || game.Players[0].SteamID != testSteamID {
+			t.Errorf("Game() = %+v", game)
+		}
+	})
+
+	t.Run("Game not found", func(t *testing.T) {
+		_, err := client.Game(ctx, testPickupSite, 2)
+
+		var respErr *api.ResponseError
+		if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound || respErr.Message != "game not found" {
+			t.Errorf("Game() error = %v, want not found response error", err)
+		}
+	})
+
+	t.Run("Predict", func(t *testing.T) {
+		prediction, err := client.Predict(ctx, testPickupSite,
+			[]api.LineupSlot{{SteamID: testSteamID, Class: "scout"}},
+			[]api.LineupSlot{{SteamID: testSteamID + 1, Class: "scout"}, {SteamID: testSteamID + 2, Class: "soldier"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if prediction.Algorithm != "openskill" || len(prediction.Red) != 1 || len(prediction.Blu) != 2 {
+			t.Fatalf("Predict() = %+v", prediction)
+		}
+		if p := prediction.RedWinProbability + prediction.BluWinProbability; p < 0.999 || p > 1.001 {
+			t.Errorf("win probabilities %v and %v don't add up to 1", prediction.RedWinProbability, prediction.BluWinProbability)
+		}
+		if prediction.Blu[1].GamesPlayed != 0 || prediction.Blu[1].Rating == 0 {
+			t.Errorf("player without rating = %+v, want default rating", prediction.Blu[1])
+		}
+	})
+
+	t.Run("Balance", func(t *testing.T) {
+		prediction, err := client.Balance(ctx, testPickupSite, []api.LineupSlot{
+			{SteamID: testSteamID, Class: "scout"},
+			{SteamID: testSteamID + 1, Class: "scout"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(prediction.Red) != 1 || len(prediction.Blu) != 1 || prediction.Red[0].SteamID == prediction.Blu[0].SteamID {
+			t.Errorf("Balance() = %+v", prediction)
+		}
+	})
+
+	t.Run("Balance odd class count", func(t *testing.T) {
+		_, err := client.Balance(ctx, testPickupSite, []api.LineupSlot{{SteamID: testSteamID, Class: "scout"}})
+
+		var respErr *api.ResponseError
+		if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("Balance() error = %v, want bad request response error", err)
+		}
+	})
+}
+
+func TestOpenAPIDescribesRoutes(t *testing.T) {
+	s := NewServer(newFakeDatabase(), Config{OrdinalK: DefaultOrdinalK})
+	baseURL := startTestServer(t, s)
+
+	resp, err := http.Get(baseURL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("failed to parse OpenAPI document: %v", err)
+	}
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want version 3", spec.OpenAPI)
+	}
+
+	param := regexp.MustCompile(`:(\w+)`)
+
+	registered := map[string]bool{}
+	for _, route := range s.app.GetRoutes(true) {
+		if !strings.HasPrefix(route.Path, "/api/v1/") || route.Method == fiber.MethodHead {
+			continue
+		}
+
+		path, method := param.ReplaceAllString(route.Path, "{$1}"), strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("route %s %s is not described", route.Method, path)
+		}
+	}
+
+	if len(registered) == 0 {
+		t.Fatal("no /api/v1 routes registered")
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if !registered[method+" "+path] {
+				t.Errorf("described operation %s %s is not registered", method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/samber/lo"
+)
+
+type comparedPlayer struct {
+	SteamID int64
+	Name    string
+}
+
+// compareRow is a class rating of both compared players, rating is empty if player has not played the class
+type compareRow struct {
+	Label   string
+	RatingA string
+	RatingB string
+	GamesA  int64
+	GamesB  int64
+}
+
+type recordLabel struct {
+	Games   int64
+	Wins    int64
+	Ties    int64
+	Losses  int64
+	WinRate string
+}
+
+func (s *Server) comparePage(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+
+	a, b, err := s.comparedPlayers(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Params("steamA") != strconv.FormatInt(a.SteamID, 10) || ctx.Params("steamB") != strconv.FormatInt(b.SteamID, 10) {
+		u := fmt.Sprintf("/%s/compare/%d/%d", pickupSite, a.SteamID, b.SteamID)
+		if query := ctx.Request().URI().QueryString(); len(query) > 0 {
+			u += "?" + string(query)
+		}
+
+		return ctx.Redirect(u, fiber.StatusMovedPermanently)
+	}
+
+	classes, gameClass, err := s.pickupSiteClasses(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	availableSites, err := s.db.GetAvailablePickupSites(ctx.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get availible pickup sites: %w", err)
+	}
+
+	ratingsA, err := s.db.GetPlayerClassRatings(ctx.Context(), pickupSite, a.SteamID)
+	if err != nil {
+		return fmt.Errorf("failed to get player's class ratings: %w", err)
+	}
+
+	ratingsB, err := s.db.GetPlayerClassRatings(ctx.Context(), pickupSite, b.SteamID)
+	if err != nil {
+		return fmt.Errorf("failed to get player's class ratings: %w", err)
+	}
+
+	headToHead, err := s.db.GetHeadToHead(ctx.Context(), pickupSite, a.SteamID, b.SteamID)
+	if err != nil {
+		return fmt.Errorf("failed to get head to head record: %w", err)
+	}
+
+	return ctx.Render("templates/compare", fiber.Map{
+		"PageTitle":      fmt.Sprintf("%s vs %s", a.Name, b.Name),
+		"PickupSite":     pickupSite,
+		"AvailableSites": availableSites,
+		"Classes":        classes,
+		"Class":          gameClass,
+		"PlayerA":        a,
+		"PlayerB":        b,
+		"Rows":           compareRows(classes, ratingsA, ratingsB),
+		"Together":       newRecordLabel(headToHead.Together),
+		"Against":        newRecordLabel(headToHead.Against),
+	})
+}
+
+// compareChart draws rating histories of both players for the class on the same chart
+func (s *Server) compareChart(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+
+	a, b, err := s.comparedPlayers(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	_, gameClass, err := s.pickupSiteClasses(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	series := make([]chartSeries, 0, 2)
+	for _, p := range []comparedPlayer{a, b} {
+		history, err := s.db.GetPlayerRatingHistoryForClass(ctx.Context(), pickupSite, p.SteamID, gameClass)
+		if err != nil {
+			return fmt.Errorf("failed to get player's history: %w", err)
+		}
+
+		series = append(series, chartSeries{Label: p.Name, Updates: history})
+	}
+
+	title := fmt.Sprintf("%s vs %s · %s · %s", a.Name, b.Name, gameClass, pickupSite)
+
+	ctx.Set(fiber.HeaderContentType, "image/svg+xml")
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=600")
+
+	return ctx.Send(renderRatingChart(title, series))
+}
+
+// comparedPlayers returns players of steamA and steamB route parameters, which must be different players
+func (s *Server) comparedPlayers(ctx *fiber.Ctx, pickupSite string) (comparedPlayer, comparedPlayer, error) {
+	var players [2]comparedPlayer
+	for i, key := range []string{"steamA", "steamB"} {
+		steamID, err := steamIDParam(ctx, key)
+		if err != nil {
+			return comparedPlayer{}, comparedPlayer{}, err
+		}
+
+		players[i].SteamID = steamID
+	}
+
+	if players[0].SteamID == players[1].SteamID {
+		return comparedPlayer{}, comparedPlayer{}, fiber.NewError(fiber.StatusBadRequest, "player can't be compared with themselves")
+	}
+
+	for i, p := range players {
+		name, err := s.db.GetPlayerName(ctx.Context(), pickupSite, p.SteamID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return comparedPlayer{}, comparedPlayer{}, fiber.ErrNotFound
+		} else if err != nil {
+			return comparedPlayer{}, comparedPlayer{}, fmt.Errorf("failed to get player's name: %w", err)
+		}
+
+		players[i].Name = name
+	}
+
+	return players[0], players[1], nil
+}
+
+// compareRows returns class ratings of both players in the order of pickup site classes followed by overall rating,
+// classes nobody of the players has played are skipped
+func compareRows(classes []classTab, ratingsA, ratingsB []db.ClassRating) []compareRow {
+	tabs := append(classes, classTab{Name: db.OverallClass, Label: "Overall"})
+
+	var rows []compareRow
+	for _, tab := range tabs {
+		a, okA := lo.Find(ratingsA, func(r db.ClassRating) bool { return r.Class == tab.Name })
+		b, okB := lo.Find(ratingsB, func(r db.ClassRating) bool { return r.Class == tab.Name })
+		if !okA && !okB {
+			continue
+		}
+
+		row := compareRow{Label: tab.Label, GamesA: a.GamesPlayed, GamesB: b.GamesPlayed}
+		if okA {
+			row.RatingA = ratingLabel(a.Rating)
+		}
+		if okB {
+			row.RatingB = ratingLabel(b.Rating)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func newRecordLabel(r db.Record) recordLabel {
+	l := recordLabel{Games: r.Games, Wins: r.Wins, Ties: r.Ties, Losses: r.Losses}
+	if r.Games > 0 {
+		l.WinRate = percentLabel(float64(r.Wins) / float64(r.Games))
+	}
+
+	return l
+}
@@ -0,0 +1,29 @@
+package http
+
+import "testing"
+
+func TestComparePage(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"different players", "/tf2pickup.test/compare/76561198011558250/76561198011558251", 200},
+		{"different players chart", "/tf2pickup.test/compare/76561198011558250/76561198011558251/chart.svg", 200},
+		{"non-canonical steam id", "/tf2pickup.test/compare/[U:1:51292522]/76561198011558251", 301},
+		{"unknown player", "/tf2pickup.test/compare/76561198011558250/76561198011558259", 404},
+		{"same player", "/tf2pickup.test/compare/76561198011558250/76561198011558250", 400},
+		{"same player in different formats", "/tf2pickup.test/compare/76561198011558250/[U:1:51292522]", 400},
+		{"same player chart", "/tf2pickup.test/compare/76561198011558250/76561198011558250/chart.svg", 400},
+	}
+
+	s := NewServer(newFakeDatabase(), Config{OrdinalK: DefaultOrdinalK})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := testRequest(t, s, "GET", tt.target, ""); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
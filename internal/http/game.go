@@ -0,0 +1,124 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type gameParticipant struct {
+	SteamID      int64
+	Name         string
+	AvatarURL    string
+	Class        string
+	RatingBefore string
+	RatingAfter  string
+	RatingDiff   string
+}
+
+type gameTeam struct {
+	Name           string
+	Score          int64
+	WinProbability string
+	Players        []gameParticipant
+}
+
+func (s *Server) gamePage(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+
+	gameID, err := ctx.ParamsInt("gameID")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to parse gameID: %s", err))
+	}
+
+	availableSites, err := s.db.GetAvailablePickupSites(ctx.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get availible pickup sites: %w", err)
+	}
+
+	game, err := s.db.GetGame(ctx.Context(), pickupSite, int64(gameID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get game: %w", err)
+	}
+
+	classes, _, err := s.pickupSiteClasses(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	ratings, err := s.db.GetGameRatings(ctx.Context(), pickupSite, int64(gameID))
+	if err != nil {
+		return fmt.Errorf("failed to get game ratings: %w", err)
+	}
+
+	// players are shown in the order of pickup site classes
+	slices.SortStableFunc(ratings, func(a, b db.GameRating) int {
+		return classTabIndex(classes, a.Class) - classTabIndex(classes, b.Class)
+	})
+
+	red := gameTeam{Name: "RED", Score: game.RedScore}
+	blu := gameTeam{Name: "BLU", Score: game.BluScore}
+
+	if p := game.RedWinProbability; p != nil {
+		red.WinProbability = percentLabel(*p)
+		blu.WinProbability = percentLabel(1 - *p)
+	}
+
+	for _, r := range ratings {
+		participant := gameParticipant{
+			SteamID:   r.SteamID,
+			Name:      r.Name,
+			AvatarURL: r.AvatarURL,
+			Class:     r.Class,
+		}
+
+		if r.RatingAfter != nil {
+			participant.RatingAfter = ratingLabel(*r.RatingAfter)
+		}
+
+		if r.RatingBefore != nil && r.RatingAfter != nil {
+			participant.RatingBefore = ratingLabel(*r.RatingBefore)
+			participant.RatingDiff = ratingDiffLabel(*r.RatingAfter, *r.RatingBefore)
+		}
+
+		switch r.Team {
+		case "red":
+			red.Players = append(red.Players, participant)
+		case "blu":
+			blu.Players = append(blu.Players, participant)
+		}
+	}
+
+	var date string
+	if ts, err := parseTimestamp(game.Ts); err == nil {
+		date = ts.Format("2006/01/02 15:04")
+	}
+
+	return ctx.Render("templates/game", fiber.Map{
+		"PageTitle":      fmt.Sprintf("Game #%d", game.ID),
+		"PickupSite":     pickupSite,
+		"AvailableSites": availableSites,
+		"Game":           game,
+		"Date":           date,
+		"Teams":          []gameTeam{red, blu},
+	})
+}
+
+func classTabIndex(classes []classTab, class string) int {
+	i := slices.IndexFunc(classes, func(c classTab) bool { return c.Name == class })
+	if i < 0 {
+		return len(classes)
+	}
+
+	return i
+}
+
+func percentLabel(p float64) string {
+	return fmt.Sprintf("%.0f%%", p*100)
+}
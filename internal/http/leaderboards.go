@@ -8,6 +8,8 @@ import (
 	"github.com/samber/lo"
 )
 
+const leaderboardPageSize = 50
+
 type winrate struct {
 	Wins   int
 	Ties   int
@@ -20,30 +22,71 @@ type rating struct {
 	Name      string
 	SteamID   int64
 	Rating    string
+	Ordinal   string
+	Games     int
 	Winrate   winrate
 }
 
 func (s *Server) leaderboardsPage(ctx *fiber.Ctx) error {
 	pickupSite := ctx.Params("pickupSite", defaultPickupSite)
-	gameClass := ctx.Query("class", defaultPlayerClass)
+
+	classes, gameClass, err := s.pickupSiteClasses(ctx, pickupSite)
+	if err != nil {
+		return err
+	}
+
+	classes = append(classes, classTab{Name: db.OverallClass, Label: "Overall"})
 
 	availableSites, err := s.db.GetAvailablePickupSites(ctx.Context())
 	if err != nil {
 		return fmt.Errorf("failed to get availible pickup sites: %w", err)
 	}
 
-	leaderboardEntries, err := s.db.GetLeaderboardForClass(ctx.Context(), gameClass, pickupSite, 0, 50)
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+
+	sortBy, ok := sortParam(ctx)
+	if !ok {
+		sortBy = db.SortByRating
+	}
+
+	minGames, err := s.db.GetPickupSiteMinGames(ctx.Context(), pickupSite)
+	if err != nil {
+		return fmt.Errorf("failed to get pickup site min games: %w", err)
+	}
+
+	q := db.LeaderboardQuery{
+		PickupSite:       pickupSite,
+		Class:            gameClass,
+		ActiveWithinDays: s.cfg.InactiveDays,
+		Provisional:      ctx.QueryBool("provisional"),
+		SortBy:           sortBy,
+		OrdinalK:         s.cfg.OrdinalK,
+		Offset:           (page - 1) * leaderboardPageSize,
+		Limit:            leaderboardPageSize,
+	}
+
+	leaderboardEntries, err := s.db.GetLeaderboardForClass(ctx.Context(), q)
+	if err != nil {
+		return err
+	}
+
+	total, err := s.db.CountLeaderboardForClass(ctx.Context(), q)
 	if err != nil {
 		return err
 	}
 
 	ratings := lo.Map(leaderboardEntries, func(e db.LeaderboardEntry, i int) rating {
 		return rating{
-			Position:  i + 1,
+			Position:  q.Offset + i + 1,
 			AvatarURL: e.AvatarURL,
 			Name:      e.Name,
 			SteamID:   e.SteamID,
 			Rating:    ratingLabel(e.Rating),
+			Ordinal:   ratingLabel(e.Ordinal),
+			Games:     int(e.GamesPlayed),
 			Winrate: winrate{
 				Wins:   int(e.GamesWon),
 				Ties:   int(e.GamesTied),
@@ -52,10 +95,71 @@ func (s *Server) leaderboardsPage(ctx *fiber.Ctx) error {
 		}
 	})
 
+	var algorithm string
+	if len(leaderboardEntries) > 0 {
+		algorithm = algorithmLabel(leaderboardEntries[0].Algorithm)
+	}
+
 	return ctx.Render("templates/leaderboards", fiber.Map{
 		"PageTitle":      "Leaderboards",
 		"PickupSite":     pickupSite,
 		"AvailableSites": availableSites,
+		"Classes":        classes,
 		"Ratings":        ratings,
+		"Algorithm":      algorithm,
+		"Class":          gameClass,
+		"SortBy":         sortBy,
+		"Provisional":    q.Provisional,
+		"MinGames":       minGames,
+		"Pagination":     newPagination(page, total),
 	})
 }
+
+// sortParam returns leaderboard sort key from sort query parameter, which defaults to rating.
+// ok is false if sort key is unknown.
+func sortParam(ctx *fiber.Ctx) (string, bool) {
+	switch sortBy := ctx.Query("sort", db.SortByRating); sortBy {
+	case db.SortByRating, db.SortByOrdinal:
+		return sortBy, true
+	default:
+		return "", false
+	}
+}
+
+type pagination struct {
+	Page     int
+	Pages    int
+	PrevPage int
+	NextPage int
+}
+
+// newPagination returns leaderboard pages navigation, PrevPage and NextPage are zero if there is no such page
+func newPagination(page, total int) pagination {
+	p := pagination{
+		Page:  page,
+		Pages: (total + leaderboardPageSize - 1) / leaderboardPageSize,
+	}
+
+	if page > 1 {
+		p.PrevPage = page - 1
+	}
+
+	if page < p.Pages {
+		p.NextPage = page + 1
+	}
+
+	return p
+}
+
+func algorithmLabel(algorithm string) string {
+	switch algorithm {
+	case "openskill":
+		return "OpenSkill (Plackett-Luce)"
+	case "elo":
+		return "Elo"
+	case "glicko2":
+		return "Glicko-2"
+	default:
+		return algorithm
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"fmt"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"github.com/samber/lo"
+)
+
+type mapStats struct {
+	Map           string
+	Games         int64
+	RedWinRate    string
+	BluWinRate    string
+	TieRate       string
+	AverageMargin string
+}
+
+type playerMapStats struct {
+	Map          string
+	Games        int64
+	Wins         int64
+	Ties         int64
+	Losses       int64
+	WinRate      string
+	RatingChange string
+}
+
+func (s *Server) mapsPage(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+
+	availableSites, err := s.db.GetAvailablePickupSites(ctx.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get availible pickup sites: %w", err)
+	}
+
+	stats, err := s.db.GetMapStats(ctx.Context(), pickupSite)
+	if err != nil {
+		return fmt.Errorf("failed to get map stats: %w", err)
+	}
+
+	return ctx.Render("templates/maps", fiber.Map{
+		"PageTitle":      "Maps",
+		"PickupSite":     pickupSite,
+		"AvailableSites": availableSites,
+		"Maps": lo.Map(stats, func(m db.MapStats, _ int) mapStats {
+			return mapStats{
+				Map:           m.Map,
+				Games:         m.Games,
+				RedWinRate:    percentLabel(float64(m.RedWins) / float64(m.Games)),
+				BluWinRate:    percentLabel(float64(m.BluWins) / float64(m.Games)),
+				TieRate:       percentLabel(float64(m.Ties) / float64(m.Games)),
+				AverageMargin: fmt.Sprintf("%.1f", m.AverageMargin),
+			}
+		}),
+	})
+}
+
+func toPlayerMapStats(m db.PlayerMapStats, _ int) playerMapStats {
+	return playerMapStats{
+		Map:          m.Map,
+		Games:        m.Games,
+		Wins:         m.Wins,
+		Ties:         m.Ties,
+		Losses:       m.Losses,
+		WinRate:      percentLabel(float64(m.Wins) / float64(m.Games)),
+		RatingChange: ratingDiffLabel(m.RatingChange, 0),
+	}
+}
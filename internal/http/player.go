@@ -2,6 +2,8 @@ package http
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 
 	"github.com/condensedtea/pickup-ratings/internal/db"
 	"github.com/gofiber/fiber/v2"
@@ -22,16 +24,50 @@ type playerRatingEntry struct {
 	Time        string
 }
 
+type playerClassRating struct {
+	Class    string
+	Label    string
+	Rating   string
+	Games    int64
+	Selected bool
+}
+
+// pairingsLimit is the number of teammates and opponents shown on player page
+const pairingsLimit = 10
+
+type pairing struct {
+	SteamID   int64
+	Name      string
+	AvatarURL string
+	Games     int64
+	Wins      int64
+	Ties      int64
+	Losses    int64
+	WinRate   string
+}
+
+// pairingsTable is rendered by pairings template, which needs pickup site and the player for links
+type pairingsTable struct {
+	PickupSite string
+	SteamID    int64
+	Pairings   []pairing
+}
+
 func (s *Server) playerPage(ctx *fiber.Ctx) error {
 	pickupSite := ctx.Params("pickupSite")
-	gameClass := ctx.Query("class", defaultPlayerClass)
 
-	steamID, err := ctx.ParamsInt("steamID")
+	steamID, err := steamIDParam(ctx, "steamID")
 	if err != nil {
-		return &fiber.Error{
-			Code:    fiber.StatusBadRequest,
-			Message: fmt.Sprintf("failed to parse steamID: %s", err),
-		}
+		return err
+	}
+
+	if ctx.Params("steamID") != strconv.FormatInt(steamID, 10) {
+		return redirectToPlayerPage(ctx, pickupSite, steamID)
+	}
+
+	classes, gameClass, err := s.pickupSiteClasses(ctx, pickupSite)
+	if err != nil {
+		return err
 	}
 
 	availableSites, err := s.db.GetAvailablePickupSites(ctx.Context())
@@ -39,32 +75,53 @@ func (s *Server) playerPage(ctx *fiber.Ctx) error {
 		return fmt.Errorf("failed to get availible pickup sites: %w", err)
 	}
 
-	history, err := s.db.GetPlayerRatingHistoryForClass(ctx.Context(), int64(steamID), gameClass)
+	history, err := s.db.GetPlayerRatingHistoryForClass(ctx.Context(), pickupSite, steamID, gameClass)
 	if err != nil {
 		return fmt.Errorf("failed to get player's history: %s", err)
 	}
 
-	playerName, err := s.db.GetPlayerName(ctx.Context(), pickupSite, int64(steamID))
+	playerName, err := s.db.GetPlayerName(ctx.Context(), pickupSite, steamID)
 	if err != nil {
 		return fmt.Errorf("failed to get player's name: %w", err)
 	}
 
-	var lastRatingValue float64
+	classRatings, err := s.db.GetPlayerClassRatings(ctx.Context(), pickupSite, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to get player's class ratings: %w", err)
+	}
+
+	mapStats, err := s.db.GetPlayerMapStats(ctx.Context(), pickupSite, steamID, gameClass)
+	if err != nil {
+		return fmt.Errorf("failed to get player's map stats: %w", err)
+	}
+
+	teammates, err := s.db.GetTeammates(ctx.Context(), pickupSite, steamID, gameClass, pairingsLimit)
+	if err != nil {
+		return fmt.Errorf("failed to get player's teammates: %w", err)
+	}
+
+	opponents, err := s.db.GetOpponents(ctx.Context(), pickupSite, steamID, gameClass, pairingsLimit)
+	if err != nil {
+		return fmt.Errorf("failed to get player's opponents: %w", err)
+	}
+
 	entries := lo.Map(history, func(u db.RatingUpdate, _ int) playerRatingEntry {
 		e := playerRatingEntry{
-			GameID:     int(u.GameID),
-			PickupID:   u.PickupID,
-			Map:        u.GameMap,
-			Rating:     ratingLabel(u.Rating),
-			RatingDiff: ratingDiffLabel(u.Rating, lastRatingValue),
-			Result:     u.Result,
-			RedScore:   int(u.RedScore),
-			BluScore:   int(u.BluScore),
-			Date:       u.Date,
-			Time:       u.Time,
+			GameID:   int(u.GameID),
+			PickupID: u.PickupID,
+			Map:      u.GameMap,
+			Rating:   ratingLabel(u.Rating),
+			Result:   u.Result,
+			RedScore: int(u.RedScore),
+			BluScore: int(u.BluScore),
+			Date:     u.Date,
+			Time:     u.Time,
+		}
+
+		if u.RatingBefore != nil {
+			e.RatingDiff = ratingDiffLabel(u.Rating, *u.RatingBefore)
 		}
 
-		lastRatingValue = u.Rating
 		return e
 	})
 
@@ -72,23 +129,78 @@ func (s *Server) playerPage(ctx *fiber.Ctx) error {
 		"PageTitle":      playerName,
 		"PickupSite":     pickupSite,
 		"AvailableSites": availableSites,
+		"Classes":        classes,
+		"Class":          gameClass,
+		"ClassRatings":   playerClassRatings(classes, classRatings, gameClass),
+		"MapStats":       lo.Map(mapStats, toPlayerMapStats),
+		"Teammates":      pairingsTable{PickupSite: pickupSite, SteamID: steamID, Pairings: lo.Map(teammates, toPairing)},
+		"Opponents":      pairingsTable{PickupSite: pickupSite, SteamID: steamID, Pairings: lo.Map(opponents, toPairing)},
 		"RatingEntries":  lo.Reverse(entries),
 		"SteamID":        steamID,
 	})
 }
 
-func ratingDiffLabel(rating float64, lastValue float64) string {
-	if lastValue == 0.0 {
-		return ratingLabel(0)
-	} else {
-		ratingDiff := rating - lastValue
-		switch {
-		case ratingDiff > 0:
-			return "+" + ratingLabel(ratingDiff)
-		case ratingDiff < 0:
-			return ratingLabel(ratingDiff)
-		default:
-			return ratingLabel(ratingDiff)
+// playerClassRatings returns player's ratings in the order of pickup site classes, overall rating goes after them
+func playerClassRatings(classes []classTab, ratings []db.ClassRating, selected string) []playerClassRating {
+	slices.SortFunc(ratings, func(a, b db.ClassRating) int {
+		return classTabIndex(classes, a.Class) - classTabIndex(classes, b.Class)
+	})
+
+	return lo.Map(ratings, func(r db.ClassRating, _ int) playerClassRating {
+		label := r.Class
+		if r.Class == db.OverallClass {
+			label = "Overall"
+		} else if i := classTabIndex(classes, r.Class); i < len(classes) {
+			label = classes[i].Label
 		}
+
+		return playerClassRating{
+			Class:    r.Class,
+			Label:    label,
+			Rating:   ratingLabel(r.Rating),
+			Games:    r.GamesPlayed,
+			Selected: r.Class == selected,
+		}
+	})
+}
+
+func toPairing(p db.Pairing, _ int) pairing {
+	return pairing{
+		SteamID:   p.SteamID,
+		Name:      p.Name,
+		AvatarURL: p.AvatarURL,
+		Games:     p.Games,
+		Wins:      p.Wins,
+		Ties:      p.Ties,
+		Losses:    p.Losses,
+		WinRate:   percentLabel(float64(p.Wins) / float64(p.Games)),
+	}
+}
+
+// playerURLPage redirects player URLs with steam id containing slashes, e.g. community profile URL
+func (s *Server) playerURLPage(ctx *fiber.Ctx) error {
+	steamID, err := steamIDParam(ctx, "*")
+	if err != nil {
+		return err
+	}
+
+	return redirectToPlayerPage(ctx, ctx.Params("pickupSite"), steamID)
+}
+
+// redirectToPlayerPage redirects to canonical player page URL with SteamID64, keeping query parameters
+func redirectToPlayerPage(ctx *fiber.Ctx, pickupSite string, steamID int64) error {
+	u := fmt.Sprintf("/%s/player/%d", pickupSite, steamID)
+	if query := ctx.Request().URI().QueryString(); len(query) > 0 {
+		u += "?" + string(query)
+	}
+
+	return ctx.Redirect(u, fiber.StatusMovedPermanently)
+}
+
+func ratingDiffLabel(rating float64, before float64) string {
+	if ratingDiff := rating - before; ratingDiff > 0 {
+		return "+" + ratingLabel(ratingDiff)
+	} else {
+		return ratingLabel(ratingDiff)
 	}
 }
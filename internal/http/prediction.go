@@ -0,0 +1,144 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/condensedtea/pickup-ratings/api"
+	"github.com/condensedtea/pickup-ratings/internal/collector"
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/samber/lo"
+)
+
+// maxLineupPlayers limits lineups of predictions and balancer, which checks every possible split
+const maxLineupPlayers = 18
+
+func (s *Server) apiPredict(ctx *fiber.Ctx) error {
+	var req api.PredictionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to parse request: %s", err))
+	}
+
+	if len(req.Red) == 0 || len(req.Blu) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "both teams must have players")
+	}
+
+	if len(req.Red)+len(req.Blu) > maxLineupPlayers {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("teams must have at most %d players in total", maxLineupPlayers))
+	}
+
+	ratings, rater, err := s.lineupRatings(ctx, ctx.Params("pickupSite"), append(slices.Clone(req.Red), req.Blu...))
+	if err != nil {
+		return err
+	}
+
+	red, blu := ratings[:len(req.Red)], ratings[len(req.Red):]
+
+	return ctx.JSON(newPrediction(rater, red, blu))
+}
+
+func (s *Server) apiBalance(ctx *fiber.Ctx) error {
+	var req api.BalanceRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to parse request: %s", err))
+	}
+
+	if len(req.Players) == 0 || len(req.Players) > maxLineupPlayers {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("number of players must be in range 1..%d", maxLineupPlayers))
+	}
+
+	ratings, rater, err := s.lineupRatings(ctx, ctx.Params("pickupSite"), req.Players)
+	if err != nil {
+		return err
+	}
+
+	red, blu, err := collector.Balance(rater, ratings)
+	if errors.Is(err, collector.ErrOddClassCount) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	} else if err != nil {
+		return err
+	}
+
+	return ctx.JSON(newPrediction(rater, red, blu))
+}
+
+// lineupRatings returns ratings of lineup players in the same order and rater of pickup site's leaderboards.
+// Players who have not played their class yet get default rating.
+func (s *Server) lineupRatings(ctx *fiber.Ctx, pickupSite string, lineup []api.LineupSlot) ([]db.PlayerRating, collector.Rater, error) {
+	classes, err := s.db.GetPickupSiteClasses(ctx.Context(), pickupSite)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, fiber.NewError(fiber.StatusNotFound, "pickup site not found")
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to get pickup site classes: %w", err)
+	}
+
+	steamIDs := lo.Map(lineup, func(slot api.LineupSlot, _ int) int64 { return slot.SteamID })
+	if len(lo.Uniq(steamIDs)) != len(steamIDs) {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "every player can be in lineup only once")
+	}
+
+	for _, slot := range lineup {
+		if !slices.Contains(classes, slot.Class) {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("unknown class %q", slot.Class))
+		}
+	}
+
+	known, err := s.db.GetPlayerRatingsForSteamIDs(ctx.Context(), steamIDs, pickupSite)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get player ratings: %w", err)
+	}
+
+	algorithm := collector.AlgorithmOpenSkill
+	switch algorithms := lo.Uniq(lo.Map(known, func(r db.PlayerRating, _ int) string { return r.Algorithm })); len(algorithms) {
+	case 0:
+	case 1:
+		algorithm = algorithms[0]
+	default:
+		return nil, nil, fiber.NewError(fiber.StatusConflict, "leaderboards of pickup site are rated with different algorithms")
+	}
+
+	rater, err := collector.NewRater(algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ratings := lo.Map(lineup, func(slot api.LineupSlot, _ int) db.PlayerRating {
+		r, ok := lo.Find(known, func(r db.PlayerRating) bool {
+			return r.SteamID == slot.SteamID && r.Class == slot.Class
+		})
+		if !ok {
+			r = rater.DefaultRating()
+			r.SteamID = slot.SteamID
+			r.Class = slot.Class
+			r.Algorithm = rater.Name()
+		}
+
+		return r
+	})
+
+	return ratings, rater, nil
+}
+
+func newPrediction(rater collector.Rater, red, blu []db.PlayerRating) api.Prediction {
+	redWinProbability := rater.WinProbability(red, blu)
+
+	return api.Prediction{
+		Algorithm:         rater.Name(),
+		RedWinProbability: redWinProbability,
+		BluWinProbability: 1 - redWinProbability,
+		Red:               lo.Map(red, toTeamPlayer),
+		Blu:               lo.Map(blu, toTeamPlayer),
+	}
+}
+
+func toTeamPlayer(r db.PlayerRating, _ int) api.TeamPlayer {
+	return api.TeamPlayer{
+		SteamID:     r.SteamID,
+		Class:       r.Class,
+		Rating:      apiRating(r.Rating),
+		GamesPlayed: r.GamesPlayed,
+	}
+}
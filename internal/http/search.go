@@ -0,0 +1,45 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/condensedtea/pickup-ratings/internal/steamid"
+	"github.com/gofiber/fiber/v2"
+)
+
+const searchResultsLimit = 50
+
+func (s *Server) searchPage(ctx *fiber.Ctx) error {
+	pickupSite := ctx.Params("pickupSite")
+	query := strings.TrimSpace(ctx.Query("q"))
+
+	availableSites, err := s.db.GetAvailablePickupSites(ctx.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get availible pickup sites: %w", err)
+	}
+
+	var players []db.Player
+	if query != "" {
+		// zero never matches any player, so only names are searched if query is not a steam id
+		steamID, _ := steamid.Parse(query)
+
+		players, err = s.db.SearchPlayers(ctx.Context(), pickupSite, query, steamID, searchResultsLimit)
+		if err != nil {
+			return fmt.Errorf("failed to search players: %w", err)
+		}
+	}
+
+	if len(players) == 1 {
+		return ctx.Redirect(fmt.Sprintf("/%s/player/%d", pickupSite, players[0].SteamID))
+	}
+
+	return ctx.Render("templates/search", fiber.Map{
+		"PageTitle":      "Search",
+		"PickupSite":     pickupSite,
+		"AvailableSites": availableSites,
+		"Query":          query,
+		"Players":        players,
+	})
+}
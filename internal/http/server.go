@@ -3,14 +3,22 @@ package http
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/condensedtea/pickup-ratings/internal/db"
+	"github.com/condensedtea/pickup-ratings/internal/steamid"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/gofiber/template/html/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/samber/lo"
 )
 
 const (
@@ -26,25 +34,51 @@ var assetFS embed.FS
 
 type database interface {
 	GetAvailablePickupSites(ctx context.Context) ([]string, error)
-	GetLeaderboardForClass(ctx context.Context, playerClass, pickupSite string, offset, limit int) ([]db.LeaderboardEntry, error)
-	GetPlayerRatingHistoryForClass(ctx context.Context, steamID int64, class string) ([]db.RatingUpdate, error)
+	GetLeaderboardForClass(ctx context.Context, q db.LeaderboardQuery) ([]db.LeaderboardEntry, error)
+	CountLeaderboardForClass(ctx context.Context, q db.LeaderboardQuery) (int, error)
+	GetPlayerRatingHistoryForClass(ctx context.Context, pickupSite string, steamID int64, class string) ([]db.RatingUpdate, error)
 	GetPlayerName(ctx context.Context, pickupSite string, steamID int64) (string, error)
+	GetPickupSiteClasses(ctx context.Context, pickupSite string) ([]string, error)
+	GetPickupSiteMinGames(ctx context.Context, pickupSite string) (int, error)
+	GetPlayerClassRatings(ctx context.Context, pickupSite string, steamID int64) ([]db.ClassRating, error)
+	GetMapStats(ctx context.Context, pickupSite string) ([]db.MapStats, error)
+	GetPlayerMapStats(ctx context.Context, pickupSite string, steamID int64, class string) ([]db.PlayerMapStats, error)
+	GetTeammates(ctx context.Context, pickupSite string, steamID int64, class string, limit int) ([]db.Pairing, error)
+	GetOpponents(ctx context.Context, pickupSite string, steamID int64, class string, limit int) ([]db.Pairing, error)
+	GetHeadToHead(ctx context.Context, pickupSite string, steamID, otherSteamID int64) (db.HeadToHead, error)
+	GetGame(ctx context.Context, pickupSite string, gameID int64) (db.Game, error)
+	GetGameLineup(ctx context.Context, pickupSite string, gameID int64) ([]db.LineupPlayer, error)
+	SearchPlayers(ctx context.Context, pickupSite, query string, steamID int64, limit int) ([]db.Player, error)
+	GetGameRatings(ctx context.Context, pickupSite string, gameID int64) ([]db.GameRating, error)
+	GetPlayerRatingsForSteamIDs(ctx context.Context, steamIDs []int64, pickupSite string) ([]db.PlayerRating, error)
+}
+
+// DefaultOrdinalK makes ordinal rating a lower bound of player's skill with 99.7% confidence
+const DefaultOrdinalK = 3
+
+type Config struct {
+	// InactiveDays hides players who have not played for more days from leaderboards, zero shows everyone
+	InactiveDays int
+	// OrdinalK is the number of uncertainties subtracted from rating to get conservative ordinal rating
+	OrdinalK float64
 }
 
 type Server struct {
-	db database
+	db  database
+	cfg Config
 
 	app *fiber.App
 }
 
-func NewServer(db database) *Server {
+func NewServer(db database, cfg Config) *Server {
 	app := fiber.New(fiber.Config{
 
-		AppName: "pickup-ratings",
-		Views:   html.NewFileSystem(http.FS(templateFS), ".tmpl"),
+		AppName:      "pickup-ratings",
+		Views:        html.NewFileSystem(http.FS(templateFS), ".tmpl"),
+		ErrorHandler: errorHandler,
 	})
 
-	s := &Server{app: app, db: db}
+	s := &Server{app: app, db: db, cfg: cfg}
 
 	s.app.Use("/assets", filesystem.New(filesystem.Config{
 		MaxAge:     3600,
@@ -52,8 +86,17 @@ func NewServer(db database) *Server {
 		PathPrefix: "assets",
 	}))
 
+	s.registerAPI()
+
 	s.app.Get("/:pickupSite?", s.leaderboardsPage)
 	s.app.Get("/:pickupSite/player/:steamID", s.playerPage)
+	s.app.Get("/:pickupSite/player/:steamID/chart.svg", s.playerChart)
+	s.app.Get("/:pickupSite/player/*", s.playerURLPage)
+	s.app.Get("/:pickupSite/search", s.searchPage)
+	s.app.Get("/:pickupSite/game/:gameID", s.gamePage)
+	s.app.Get("/:pickupSite/maps", s.mapsPage)
+	s.app.Get("/:pickupSite/compare/:steamA/:steamB", s.comparePage)
+	s.app.Get("/:pickupSite/compare/:steamA/:steamB/chart.svg", s.compareChart)
 
 	return s
 }
@@ -62,6 +105,56 @@ func (s *Server) Run(port string) error {
 	return s.app.Listen(":" + port)
 }
 
+type classTab struct {
+	Name  string
+	Label string
+}
+
+// pickupSiteClasses returns class tabs of pickup site and selected class,
+// which is taken from class query parameter or defaults to scout or first class of the site
+func (s *Server) pickupSiteClasses(ctx *fiber.Ctx, pickupSite string) ([]classTab, string, error) {
+	classes, err := s.db.GetPickupSiteClasses(ctx.Context(), pickupSite)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", fiber.ErrNotFound
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to get pickup site classes: %w", err)
+	}
+
+	selected := ctx.Query("class")
+	if selected == "" {
+		selected = defaultPlayerClass
+		if !slices.Contains(classes, selected) && len(classes) > 0 {
+			selected = classes[0]
+		}
+	}
+
+	tabs := lo.Map(classes, func(class string, _ int) classTab {
+		return classTab{Name: class, Label: strings.ToUpper(class[:1]) + class[1:]}
+	})
+
+	return tabs, selected, nil
+}
+
+// steamIDParam parses route parameter with steam id in any format supported by steamid package
+func steamIDParam(ctx *fiber.Ctx, key string) (int64, error) {
+	raw, err := url.PathUnescape(ctx.Params(key))
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to parse %s: %s", key, err))
+	}
+
+	steamID, err := steamid.Parse(raw)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to parse %s: %s", key, err))
+	}
+
+	return steamID, nil
+}
+
+// parseTimestamp parses postgres timestamp text, all timestamps are stored in UTC
+func parseTimestamp(ts string) (time.Time, error) {
+	return time.Parse("2006-01-02 15:04:05.999999", ts)
+}
+
 func ratingLabel(v float64) string {
 	return fmt.Sprintf("%.0f", math.Round(v*100))
 }
@@ -0,0 +1,81 @@
+// Package steamid parses Steam IDs of individual accounts written in common formats.
+package steamid
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// baseID is SteamID64 of individual account with account ID 0
+const baseID = 76561197960265728
+
+var (
+	ErrInvalid = errors.New("invalid steam id")
+
+	steamID2Re = regexp.MustCompile(`^STEAM_[01]:([01]):(\d+)$`)
+	steamID3Re = regexp.MustCompile(`^\[?U:1:(\d+)]?$`)
+)
+
+// Parse returns SteamID64 from SteamID64, SteamID2 (STEAM_0:1:6172), SteamID3 ([U:1:12345])
+// or community profile URL (https://steamcommunity.com/profiles/76561197960278073).
+func Parse(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "steamcommunity.com/") || strings.HasPrefix(s, "www.steamcommunity.com/") {
+		s = "https://" + s
+	}
+
+	if u, err := url.Parse(s); err == nil && (u.Host == "steamcommunity.com" || u.Host == "www.steamcommunity.com") {
+		path := strings.Trim(u.Path, "/")
+		if !strings.HasPrefix(path, "profiles/") {
+			return 0, fmt.Errorf("%w: %q is not a profile URL", ErrInvalid, s)
+		}
+
+		s = strings.TrimPrefix(path, "profiles/")
+	}
+
+	// prefixes of SteamID2 and SteamID3 are case-insensitive
+	upper := strings.ToUpper(s)
+
+	if m := steamID2Re.FindStringSubmatch(upper); m != nil {
+		accountID, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalid, err)
+		}
+
+		// lowest bit of account id is stored separately
+		return fromAccountID(accountID*2 + int64(m[1][0]-'0'))
+	}
+
+	if m := steamID3Re.FindStringSubmatch(upper); m != nil {
+		accountID, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalid, err)
+		}
+
+		return fromAccountID(accountID)
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	if id <= baseID || id > baseID+1<<32-1 {
+		return 0, fmt.Errorf("%w: %d is not SteamID64 of individual account", ErrInvalid, id)
+	}
+
+	return id, nil
+}
+
+func fromAccountID(accountID int64) (int64, error) {
+	if accountID <= 0 || accountID > 1<<32-1 {
+		return 0, fmt.Errorf("%w: account id %d is out of range", ErrInvalid, accountID)
+	}
+
+	return baseID + accountID, nil
+}
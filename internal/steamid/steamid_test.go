@@ -0,0 +1,75 @@
+package steamid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	const steamID = 76561197960278073
+
+	tests := []struct {
+		name  string
+		input string
+		want  int64
+	}{
+		{"SteamID64", "76561197960278073", steamID},
+		{"SteamID64 with spaces", " 76561197960278073\n", steamID},
+		{"SteamID2 universe 0", "STEAM_0:1:6172", steamID},
+		{"SteamID2 universe 1", "STEAM_1:1:6172", steamID},
+		{"SteamID2 even account", "STEAM_0:0:6172", steamID - 1},
+		{"SteamID2 lowercase", "steam_0:1:6172", steamID},
+		{"SteamID3", "[U:1:12345]", steamID},
+		{"SteamID3 without brackets", "U:1:12345", steamID},
+		{"SteamID3 lowercase", "[u:1:12345]", steamID},
+		{"profile URL", "https://steamcommunity.com/profiles/76561197960278073", steamID},
+		{"profile URL with trailing slash", "https://steamcommunity.com/profiles/76561197960278073/", steamID},
+		{"profile URL with http", "http://www.steamcommunity.com/profiles/76561197960278073", steamID},
+		{"profile URL without scheme", "steamcommunity.com/profiles/76561197960278073", steamID},
+		{"profile URL without scheme with trailing slash", "www.steamcommunity.com/profiles/76561197960278073/", steamID},
+		{"profile URL with SteamID3", "https://steamcommunity.com/profiles/[U:1:12345]", steamID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"name", "condensedtea"},
+		{"negative", "-76561197960278073"},
+		{"SteamID64 of account 0", "76561197960265728"},
+		{"SteamID64 out of individual range", "76561202255233024"},
+		{"account id", "12345"},
+		{"SteamID2 with invalid bit", "STEAM_0:2:6172"},
+		{"SteamID2 with invalid universe", "STEAM_2:1:6172"},
+		{"SteamID3 of account 0", "[U:1:0]"},
+		{"SteamID3 of group", "[g:1:12345]"},
+		{"vanity profile URL", "https://steamcommunity.com/id/condensedtea"},
+		{"other site URL", "https://example.com/profiles/76561197960278073"},
+		{"lookalike site URL", "https://evilsteamcommunity.com/profiles/76561197960278073"},
+		{"steam subdomain of other site URL", "https://steamcommunity.com.example.com/profiles/76561197960278073"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Parse(tt.input); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) = %d, %v, want ErrInvalid", tt.input, got, err)
+			}
+		})
+	}
+}
@@ -57,38 +57,52 @@ func (c *Client) LoadNewGames(ctx context.Context, startingOffset, limit int) ([
 }
 
 func (c *Client) loadResultsPage(ctx context.Context, limit, offset int) ([]Result, int64, error) {
+	type results struct {
+		Results   []Result `json:"results"`
+		ItemCount int64    `json:"itemCount"`
+	}
+
+	var v results
+	if err := c.get(ctx, "/games", fmt.Sprintf("limit=%d&offset=%d&sort=launchedAt", limit, offset), &v); err != nil {
+		return nil, 0, err
+	}
+
+	return v.Results, v.ItemCount, nil
+}
+
+// LoadGame loads single game by its pickup ID
+func (c *Client) LoadGame(ctx context.Context, id string) (Result, error) {
+	var v Result
+	if err := c.get(ctx, "/games/"+url.PathEscape(id), "", &v); err != nil {
+		return Result{}, err
+	}
+
+	return v, nil
+}
+
+func (c *Client) get(ctx context.Context, path, rawQuery string, v any) error {
 	u := url.URL{
 		Scheme:   "https",
 		Host:     c.apiHost,
-		Path:     "/games",
-		RawQuery: fmt.Sprintf("limit=%d&offset=%d&sort=launchedAt", limit, offset),
+		Path:     path,
+		RawQuery: rawQuery,
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
 	if err != nil {
-		return nil, 0, fmt.Errorf("preparing http request: %w", err)
+		return fmt.Errorf("preparing http request: %w", err)
 	}
 
 	resp, err := c.tr.RoundTrip(req)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBytes, _ := io.ReadAll(resp.Body)
-		return nil, 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBytes))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBytes))
 	}
 
-	type results struct {
-		Results   []Result `json:"results"`
-		ItemCount int64    `json:"itemCount"`
-	}
-
-	var v results
-	if err = json.NewDecoder(resp.Body).Decode(&v); err != nil {
-		return nil, 0, err
-	}
-
-	return v.Results, v.ItemCount, nil
+	return json.NewDecoder(resp.Body).Decode(v)
 }
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- lineup of single game, used to replay games without loading them from pickup API again
+create table game_players (
+    game_id int not null,
+    pickup_site text not null,
+    steam_id bigint not null,
+    team text not null,
+    game_class text not null,
+
+    primary key (game_id, pickup_site, steam_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table game_players;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- state wasn't tracked before this migration, so games are only known to have
+-- ended normally if they have rated anyone; the rest are marked as unknown
+alter table game_history add column state text not null default 'ended';
+
+update game_history gh set state = 'unknown'
+where not exists (
+    select 1 from player_rating_history rh
+    where rh.game_id = gh.game_id and rh.pickup_site = gh.pickup_site
+);
+
+create index game_players_steam_id_idx on game_players (pickup_site, steam_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index game_players_steam_id_idx;
+
+alter table game_history drop column state;
+-- +goose StatementEnd
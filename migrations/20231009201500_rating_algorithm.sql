@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- rating algorithm that produced leaderboard values, all existing leaderboards were rated with OpenSkill
+alter table player_leaderboard add column algorithm text not null default 'openskill';
+
+-- used only by Glicko-2
+alter table player_leaderboard add column volatility float4 not null default 0.06;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table player_leaderboard drop column volatility;
+
+alter table player_leaderboard drop column algorithm;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table player_leaderboard add column last_played_at timestamp;
+
+-- time up to which uncertainty_value includes inactivity growth
+alter table player_leaderboard add column uncertainty_updated_at timestamp;
+
+update player_leaderboard l set
+    last_played_at = h.ts,
+    uncertainty_updated_at = h.ts
+from (select leaderboard_id, max(ts) as ts from player_rating_history group by leaderboard_id) h
+where h.leaderboard_id = l.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table player_leaderboard drop column uncertainty_updated_at;
+
+alter table player_leaderboard drop column last_played_at;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- per site configuration, classes are listed in the order they are shown on the website
+create table pickup_sites (
+    name text primary key,
+    classes text[] not null default '{}'
+);
+
+-- all sites before this migration were 6v6
+insert into pickup_sites(name, classes)
+select distinct pickup_site, '{scout,soldier,demoman,medic}'::text[] from game_history;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table pickup_sites;
+-- +goose StatementEnd
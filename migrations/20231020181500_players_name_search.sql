@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+create extension if not exists pg_trgm;
+
+create index players_name_trgm_idx on players using gin (name gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index players_name_trgm_idx;
+-- +goose StatementEnd
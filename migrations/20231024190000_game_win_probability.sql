@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- probability of red team winning the game assigned by rating algorithm before the game was rated
+alter table game_history add column red_win_probability float4;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table game_history drop column red_win_probability;
+-- +goose StatementEnd
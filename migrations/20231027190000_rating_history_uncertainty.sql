@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- uncertainty after the game, it is unknown for updates logged before this migration
+alter table player_rating_history add column uncertainty_after float4;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table player_rating_history drop column uncertainty_after;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table player_rating_history add column rating_before float4;
+alter table player_rating_history add column uncertainty_before float4;
+
+-- values before the game are restored from previous update of the leaderboard,
+-- first updates of leaderboards logged before this migration are left unknown until ratings are recomputed
+update player_rating_history rh set
+    rating_before = prev.rating_before,
+    uncertainty_before = prev.uncertainty_before
+from (
+    select
+        id,
+        lag(rating_value) over (partition by leaderboard_id order by id) as rating_before,
+        lag(uncertainty_after) over (partition by leaderboard_id order by id) as uncertainty_before
+    from player_rating_history
+) prev
+where prev.id = rh.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table player_rating_history drop column uncertainty_before;
+alter table player_rating_history drop column rating_before;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- players with fewer games are provisional and are not shown on leaderboards,
+-- default keeps leaderboards showing players with more than 15 games as before
+alter table pickup_sites add column min_games int not null default 16;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table pickup_sites drop column min_games;
+-- +goose StatementEnd
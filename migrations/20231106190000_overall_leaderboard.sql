@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- class leaderboards together with overall leaderboard of every player, overall rating and uncertainty
+-- are averages of player's class values weighted by games played on the class
+create view leaderboard_entries as
+select
+    pickup_site,
+    player_steam_id,
+    player_class,
+    rating,
+    uncertainty_value,
+    games_played,
+    games_tied,
+    games_won,
+    algorithm,
+    last_played_at
+from player_leaderboard
+union all
+select
+    pickup_site,
+    player_steam_id,
+    'overall',
+    (sum(rating * games_played) / sum(games_played))::float4,
+    (sum(uncertainty_value * games_played) / sum(games_played))::float4,
+    sum(games_played)::bigint,
+    sum(games_tied)::bigint,
+    sum(games_won)::bigint,
+    min(algorithm),
+    max(last_played_at)
+from player_leaderboard
+where games_played > 0
+group by pickup_site, player_steam_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop view leaderboard_entries;
+-- +goose StatementEnd